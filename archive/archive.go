@@ -0,0 +1,226 @@
+// Package archive safely extracts the archives chains are shipped in and
+// writes the backups the launcher makes.
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type Format string
+
+const (
+	Zip   Format = "zip"
+	TarGz Format = "tar.gz"
+	TarXz Format = "tar.xz"
+)
+
+// Extract extracts the archive at src into dst, dropping the first strip
+// path elements of every entry like tar --strip-components. Entries that
+// would end up outside dst, through their path or through symlinks, fail the
+// extraction. The archive is extracted into a staging directory next to dst
+// which then replaces dst, so dst is never left half extracted.
+func Extract(src string, dst string, format Format, strip int) error {
+	var extract func(string, *writer) error
+	switch format {
+	case Zip:
+		extract = extractZip
+	case TarGz:
+		extract = extractTarGz
+	case TarXz:
+		extract = extractTarXz
+	default:
+		return fmt.Errorf("unknown archive format %q", format)
+	}
+
+	dst = filepath.Clean(dst)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(dst), filepath.Base(dst)+".staging-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	w := &writer{root: staging, strip: strip}
+	if err := extract(src, w); err != nil {
+		return fmt.Errorf("%s: %w", src, err)
+	}
+	if err := w.finish(); err != nil {
+		return fmt.Errorf("%s: %w", src, err)
+	}
+	if err := os.Chmod(staging, 0o755); err != nil {
+		return err
+	}
+	return replaceDir(staging, dst)
+}
+
+// replaceDir moves dir to dst, replacing whatever is at dst.
+func replaceDir(dir string, dst string) error {
+	old := dst + ".old"
+	if err := os.RemoveAll(old); err != nil {
+		return err
+	}
+	if err := os.Rename(dst, old); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Rename(dir, dst); err != nil {
+		os.Rename(old, dst)
+		return err
+	}
+	return os.RemoveAll(old)
+}
+
+type symlink struct {
+	name   string
+	target string
+}
+
+// writer creates the entries of an archive below root.
+type writer struct {
+	root  string
+	strip int
+	// Created last, so no entry can be written through a symlink
+	symlinks []symlink
+}
+
+// path returns where entry name is extracted to, or "" if nothing is left
+// of name after stripping.
+func (w *writer) path(name string) (string, error) {
+	rel := path.Clean(strings.TrimPrefix(filepath.ToSlash(name), "./"))
+	if rel == "." {
+		return "", nil
+	}
+	// Rejects absolute paths and paths starting with ..
+	if !filepath.IsLocal(filepath.FromSlash(rel)) {
+		return "", fmt.Errorf("unsafe path %s", name)
+	}
+	parts := strings.Split(rel, "/")
+	if len(parts) <= w.strip {
+		return "", nil
+	}
+	return filepath.Join(w.root, filepath.FromSlash(strings.Join(parts[w.strip:], "/"))), nil
+}
+
+func (w *writer) dir(name string) error {
+	p, err := w.path(name)
+	if err != nil || p == "" {
+		return err
+	}
+	return os.MkdirAll(p, 0o755)
+}
+
+// file writes r to entry name with the permissions in mode.
+func (w *writer) file(name string, mode fs.FileMode, r io.Reader) error {
+	p, err := w.path(name)
+	if err != nil || p == "" {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	if fi, err := os.Lstat(p); err == nil && !fi.Mode().IsRegular() {
+		return fmt.Errorf("%s is in the archive twice", name)
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if mode.Perm() == 0 {
+		mode = 0o644
+	}
+	// Set after writing as the umask applies to OpenFile
+	return os.Chmod(p, mode.Perm())
+}
+
+// link hard links entry name to the already extracted entry target.
+func (w *writer) link(name string, target string) error {
+	p, err := w.path(name)
+	if err != nil || p == "" {
+		return err
+	}
+	t, err := w.path(target)
+	if err != nil {
+		return err
+	}
+	if t == "" {
+		return fmt.Errorf("%s links to %s, which is stripped", name, target)
+	}
+	fi, err := os.Lstat(t)
+	if err != nil || !fi.Mode().IsRegular() {
+		return fmt.Errorf("%s links to %s, which is not a file extracted before it", name, target)
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	return os.Link(t, p)
+}
+
+// symlink queues a symlink, target must be relative and stay inside the
+// archive.
+func (w *writer) symlink(name string, target string) error {
+	p, err := w.path(name)
+	if err != nil || p == "" {
+		return err
+	}
+	rel, _ := filepath.Rel(w.root, p)
+	if filepath.IsAbs(target) || !filepath.IsLocal(filepath.Join(filepath.Dir(rel), target)) {
+		return fmt.Errorf("symlink %s points outside the archive to %s", name, target)
+	}
+	w.symlinks = append(w.symlinks, symlink{name: p, target: target})
+	return nil
+}
+
+// finish creates the symlinks and checks none of them, through other
+// symlinks, resolves to outside root.
+func (w *writer) finish() error {
+	for _, l := range w.symlinks {
+		if err := os.MkdirAll(filepath.Dir(l.name), 0o755); err != nil {
+			return err
+		}
+		if err := os.Symlink(l.target, l.name); err != nil {
+			return err
+		}
+	}
+	root, err := filepath.EvalSymlinks(w.root)
+	if err != nil {
+		return err
+	}
+	for _, l := range w.symlinks {
+		// Dangling links are checked from where they are created
+		dir, err := filepath.EvalSymlinks(filepath.Dir(l.name))
+		if err != nil {
+			return err
+		}
+		resolved := filepath.Join(dir, l.target)
+		if r, err := filepath.EvalSymlinks(l.name); err == nil {
+			resolved = r
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if !within(root, resolved) {
+			rel, _ := filepath.Rel(w.root, l.name)
+			return fmt.Errorf("symlink %s points outside the archive", rel)
+		}
+	}
+	return nil
+}
+
+func within(root string, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	return err == nil && (rel == "." || filepath.IsLocal(rel))
+}
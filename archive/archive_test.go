@@ -0,0 +1,152 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type entry struct {
+	name string
+	typ  byte // tar.TypeReg, TypeDir, TypeSymlink or TypeLink
+	body string
+	link string
+}
+
+func writeTestTarGz(t *testing.T, entries []entry) string {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), "test.tar.gz")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := gzip.NewWriter(f)
+	tw := tar.NewWriter(zw)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typ, Linkname: e.link, Mode: 0o755}
+		if e.typ == tar.TypeReg {
+			hdr.Size = int64(len(e.body))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if e.typ == tar.TypeReg {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return src
+}
+
+func TestExtractTarGz(t *testing.T) {
+	src := writeTestTarGz(t, []entry{
+		{name: "pkg-1.0/", typ: tar.TypeDir},
+		{name: "pkg-1.0/bin/chaind", typ: tar.TypeReg, body: "binary"},
+		{name: "pkg-1.0/bin/chain-cli", typ: tar.TypeLink, link: "pkg-1.0/bin/chaind"},
+		{name: "pkg-1.0/lib/libchain.so", typ: tar.TypeSymlink, link: "libchain.so.1"},
+		{name: "pkg-1.0/lib/libchain.so.1", typ: tar.TypeReg, body: "library"},
+	})
+	dst := filepath.Join(t.TempDir(), "out")
+	if err := os.MkdirAll(filepath.Join(dst, "stale"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Extract(src, dst, TarGz, 1); err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	files := map[string]string{
+		"bin/chaind":      "binary",
+		"bin/chain-cli":   "binary",
+		"lib/libchain.so": "library",
+	}
+	for name, want := range files {
+		b, err := os.ReadFile(filepath.Join(dst, name))
+		if err != nil || string(b) != want {
+			t.Errorf("%s = %q, %v, want %q", name, b, err, want)
+		}
+	}
+	if fi, err := os.Stat(filepath.Join(dst, "bin/chaind")); err != nil || fi.Mode().Perm() != 0o755 {
+		t.Errorf("bin/chaind mode = %v, %v, want 0755", fi.Mode(), err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "stale")); !os.IsNotExist(err) {
+		t.Error("dst was not replaced")
+	}
+}
+
+func TestExtractRejectsUnsafeEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []entry
+	}{
+		{"parent path", []entry{{name: "../evil", typ: tar.TypeReg, body: "x"}}},
+		{"nested parent path", []entry{{name: "pkg/../../evil", typ: tar.TypeReg, body: "x"}}},
+		{"absolute path", []entry{{name: "/tmp/evil", typ: tar.TypeReg, body: "x"}}},
+		{"absolute symlink", []entry{{name: "etc", typ: tar.TypeSymlink, link: "/etc"}}},
+		{"symlink to parent", []entry{{name: "pkg/up", typ: tar.TypeSymlink, link: "../.."}}},
+		{"symlink chain", []entry{
+			{name: "sub/", typ: tar.TypeDir},
+			{name: "sub/l2", typ: tar.TypeSymlink, link: ".."},
+			{name: "l1", typ: tar.TypeSymlink, link: "sub/l2/.."},
+		}},
+		{"hard link outside", []entry{{name: "passwd", typ: tar.TypeLink, link: "../etc/passwd"}}},
+		{"hard link to missing", []entry{{name: "a", typ: tar.TypeLink, link: "b"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := writeTestTarGz(t, tt.entries)
+			parent := t.TempDir()
+			dst := filepath.Join(parent, "out")
+			if err := os.MkdirAll(dst, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dst, "keep"), []byte("kept"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := Extract(src, dst, TarGz, 0); err == nil {
+				t.Fatal("Extract succeeded")
+			}
+			// A failed extraction leaves dst alone and nothing next to it
+			if b, err := os.ReadFile(filepath.Join(dst, "keep")); err != nil || string(b) != "kept" {
+				t.Errorf("dst changed: %q, %v", b, err)
+			}
+			if entries, _ := os.ReadDir(parent); len(entries) != 1 {
+				t.Errorf("left behind next to dst: %v", entries)
+			}
+		})
+	}
+}
+
+func TestExtractZipRejectsParentPath(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "test.zip")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, err := zw.Create("../evil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("x"))
+	zw.Close()
+	f.Close()
+
+	dst := filepath.Join(t.TempDir(), "out")
+	if err := Extract(src, dst, Zip, 0); err == nil {
+		t.Fatal("Extract succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dst), "evil")); !os.IsNotExist(err) {
+		t.Error("evil was written outside dst")
+	}
+}
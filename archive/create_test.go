@@ -0,0 +1,64 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateTarGzRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	for name, body := range map[string]string{
+		"chain/blocks/blk00000.dat": "blocks",
+		"chain/wallet.dat":          "wallet",
+		"other/unrelated":           "skipped",
+	} {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("wallet.dat", filepath.Join(root, "chain", "wallet.link")); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "backup.tar.gz")
+	if err := CreateTarGz(dst, root, []string{filepath.Join(root, "chain")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dst + ".partial"); !os.IsNotExist(err) {
+		t.Errorf("partial archive left behind: %v", err)
+	}
+	out := filepath.Join(t.TempDir(), "out")
+	if err := Extract(dst, out, TarGz, 0); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"chain/blocks/blk00000.dat": "blocks",
+		"chain/wallet.dat":          "wallet",
+		"chain/wallet.link":         "wallet",
+	} {
+		b, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil || string(b) != want {
+			t.Errorf("%s = %q, %v, want %q", name, b, err, want)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(out, "other")); !os.IsNotExist(err) {
+		t.Errorf("other was archived: %v", err)
+	}
+}
+
+func TestCreateTarGzRejectsPathsOutsideRoot(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	dst := filepath.Join(t.TempDir(), "backup.tar.gz")
+	if err := CreateTarGz(dst, root, []string{outside}); err == nil {
+		t.Fatal("archived a path outside root")
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("archive written: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/ulikunitz/xz"
+)
+
+func extractTarGz(src string, w *writer) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	return extractTar(zr, w)
+}
+
+func extractTarXz(src string, w *writer) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	xr, err := xz.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return err
+	}
+	return extractTar(xr, w)
+}
+
+func extractTar(r io.Reader, w *writer) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = w.dir(hdr.Name)
+		case tar.TypeReg, tar.TypeRegA:
+			err = w.file(hdr.Name, hdr.FileInfo().Mode(), tr)
+		case tar.TypeSymlink:
+			err = w.symlink(hdr.Name, hdr.Linkname)
+		case tar.TypeLink:
+			err = w.link(hdr.Name, hdr.Linkname)
+		}
+		// Devices, pipes and the like have no place in a chain package
+		if err != nil {
+			return err
+		}
+	}
+}
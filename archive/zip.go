@@ -0,0 +1,50 @@
+package archive
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+)
+
+func extractZip(src string, w *writer) error {
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if err := extractZipFile(f, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractZipFile(f *zip.File, w *writer) error {
+	mode := f.Mode()
+	switch {
+	case mode.IsDir():
+		return w.dir(f.Name)
+	case mode&fs.ModeSymlink != 0:
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		// The target is stored as the content of the entry
+		target, err := io.ReadAll(io.LimitReader(r, 4096))
+		if err != nil {
+			return err
+		}
+		return w.symlink(f.Name, string(target))
+	case mode.IsRegular():
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		return w.file(f.Name, mode, r)
+	}
+	// Devices, pipes and the like have no place in a chain package
+	return nil
+}
@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"dc-launcher/archive"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// WalletBackup is a wallet archive in the backups directory.
+type WalletBackup struct {
+	Chain     string    `json:"chain"`
+	Path      string    `json:"path"`
+	Time      time.Time `json:"time"`
+	Size      int64     `json:"size"`
+	Encrypted bool      `json:"encrypted,omitempty"`
+}
+
+type BackupOptions struct {
+	// Encrypt the archive with this passphrase if set
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+type RestoreOptions struct {
+	File string `json:"file"`
+	// Passphrase of an encrypted backup, the wallet it replaces is
+	// archived with it too
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+const (
+	walletBackupExt = ".tar.gz"
+	encryptedExt    = ".enc"
+	// Directory in the wallet directory a backup is extracted to before it
+	// replaces the wallet
+	walletRestoreDir = ".wallet-restore"
+	// How long drivechain gets to answer after a restore before the
+	// sidechains stopped with it are started again
+	restartTimeout = 2 * time.Minute
+)
+
+// BackupWallet archives the wallet of chain id to the backups directory.
+// A chain answering RPC calls writes the backup itself with backupwallet,
+// otherwise the wallet files are copied, which is refused while the
+// chain's process is alive and may be writing them.
+func BackupWallet(as *AppState, id string, opts BackupOptions) (WalletBackup, error) {
+	cd, _, err := chainByID(as, id)
+	if err != nil {
+		return WalletBackup{}, err
+	}
+	dir, err := backupsDir()
+	if err != nil {
+		return WalletBackup{}, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return WalletBackup{}, err
+	}
+
+	root, names := cd.Driver().WalletFiles(cd)
+	paths := existingPaths(root, names)
+	switch {
+	case chainAnswers(cd):
+		staging, err := os.MkdirTemp(dir, ".wallet-")
+		if err != nil {
+			return WalletBackup{}, err
+		}
+		defer os.RemoveAll(staging)
+		if err := rpcBackupWallet(cd, staging); err != nil {
+			return WalletBackup{}, err
+		}
+		root = staging
+		if paths, err = pathsExcept(staging, nil); err != nil {
+			return WalletBackup{}, err
+		}
+	case cd.Driver().SupportsRPC() && chainProcessAlive(as, cd):
+		return WalletBackup{}, fmt.Errorf("%s is running but does not answer RPC calls yet, try again once it does", id)
+	case chainProcessAlive(as, cd):
+		return WalletBackup{}, fmt.Errorf("%s is running and may be writing its wallet, stop it before backing it up", id)
+	case len(paths) == 0:
+		return WalletBackup{}, fmt.Errorf("%s has no wallet to back up", id)
+	}
+	return writeWalletBackup(cd, "wallet", root, paths, opts.Passphrase)
+}
+
+// rpcBackupWallet has the chain write a copy of its wallet to dir, at the
+// path the wallet has in the network directory.
+func rpcBackupWallet(cd *ChainData, dir string) error {
+	rel, err := rpcWalletFile(cd)
+	if err != nil {
+		return err
+	}
+	dst := filepath.Join(dir, rel)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o700); err != nil {
+		return err
+	}
+	println("Backing up the wallet of " + cd.ID + " with backupwallet")
+	c := cd.RPCClient()
+	// Large wallets take a while to copy
+	c.Timeout = time.Minute
+	_, err = c.Do(context.Background(), "backupwallet", dst)
+	return err
+}
+
+// rpcWalletFile returns the path of the loaded wallet relative to the
+// network directory, resolved like Bitcoin Core does: in the wallets
+// directory if there is one, and wallet.dat inside the wallet's directory
+// for wallets that are directories.
+func rpcWalletFile(cd *ChainData) (string, error) {
+	ctx, cancel := rpcContext()
+	defer cancel()
+	res, err := cd.RPCClient().Do(ctx, "getwalletinfo")
+	if err != nil {
+		return "", err
+	}
+	var info struct {
+		WalletName string `json:"walletname"`
+	}
+	if err := json.Unmarshal(res, &info); err != nil {
+		return "", err
+	}
+	if info.WalletName != "" && !filepath.IsLocal(info.WalletName) {
+		return "", fmt.Errorf("%s: wallet %q is outside the data directory", cd.ID, info.WalletName)
+	}
+	walletDir := ""
+	if fi, err := os.Stat(filepath.Join(cd.NetDir, "wallets")); err == nil && fi.IsDir() {
+		walletDir = "wallets"
+	}
+	rel := filepath.Join(walletDir, info.WalletName)
+	if fi, err := os.Stat(filepath.Join(cd.NetDir, rel)); err == nil && fi.IsDir() {
+		rel = filepath.Join(rel, "wallet.dat")
+	}
+	return rel, nil
+}
+
+// writeWalletBackup archives paths with names relative to root, so a
+// backup can be restored to another data directory, and encrypts the
+// archive if passphrase is set.
+func writeWalletBackup(cd *ChainData, what string, root string, paths []string, passphrase string) (WalletBackup, error) {
+	dir, err := backupsDir()
+	if err != nil {
+		return WalletBackup{}, err
+	}
+	dst := filepath.Join(dir, backupName(cd, what, walletBackupExt))
+	println("Backing up the wallet of " + cd.ID + " to " + dst)
+	if passphrase == "" {
+		if err := archive.CreateTarGz(dst, root, paths); err != nil {
+			return WalletBackup{}, err
+		}
+		return walletBackupInfo(cd.ID, dst)
+	}
+
+	plain := filepath.Join(dir, "."+filepath.Base(dst))
+	defer os.Remove(plain)
+	if err := archive.CreateTarGz(plain, root, paths); err != nil {
+		return WalletBackup{}, err
+	}
+	dst += encryptedExt
+	if err := encryptBackup(plain, dst, passphrase); err != nil {
+		return WalletBackup{}, err
+	}
+	return walletBackupInfo(cd.ID, dst)
+}
+
+func walletBackupInfo(id string, path string) (WalletBackup, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return WalletBackup{}, err
+	}
+	return WalletBackup{
+		Chain:     id,
+		Path:      path,
+		Time:      fi.ModTime(),
+		Size:      fi.Size(),
+		Encrypted: strings.HasSuffix(path, encryptedExt),
+	}, nil
+}
+
+// WalletBackups lists the wallet backups of chain id, newest first.
+func WalletBackups(as *AppState, id string) ([]WalletBackup, error) {
+	if _, _, err := chainByID(as, id); err != nil {
+		return nil, err
+	}
+	dir, err := backupsDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var backups []WalletBackup
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() || !strings.HasPrefix(name, id+"-wallet-") {
+			continue
+		}
+		if !strings.HasSuffix(name, walletBackupExt) && !strings.HasSuffix(name, walletBackupExt+encryptedExt) {
+			continue
+		}
+		b, err := walletBackupInfo(id, filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, b)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// RestoreWallet replaces the wallet of chain id with a backup. The backup
+// is extracted and checked before the chain is stopped, the wallet it
+// replaces is backed up as <chain>-wallet-replaced-<time>, and the chains
+// that were stopped for it, sidechains stopped along with drivechain
+// included, are started again.
+func RestoreWallet(as *AppState, id string, opts RestoreOptions) (WalletBackup, error) {
+	cd, cs, err := chainByID(as, id)
+	if err != nil {
+		return WalletBackup{}, err
+	}
+	root, names := cd.Driver().WalletFiles(cd)
+	if root == "" {
+		return WalletBackup{}, fmt.Errorf("%s has no data directory", id)
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return WalletBackup{}, err
+	}
+
+	// Extracted next to the wallet, so the files are only renamed into
+	// place once the chain stopped
+	staging := filepath.Join(root, walletRestoreDir)
+	defer os.RemoveAll(staging)
+	src := opts.File
+	encrypted, err := isEncryptedBackup(src)
+	if err != nil {
+		return WalletBackup{}, err
+	}
+	if encrypted {
+		if opts.Passphrase == "" {
+			return WalletBackup{}, fmt.Errorf("%s is encrypted, a passphrase is needed", src)
+		}
+		src = staging + walletBackupExt
+		defer os.Remove(src)
+		if err := decryptBackup(opts.File, src, opts.Passphrase); err != nil {
+			return WalletBackup{}, err
+		}
+	}
+	if err := archive.Extract(src, staging, archive.TarGz, 0); err != nil {
+		return WalletBackup{}, err
+	}
+	restored, err := checkWalletBackup(staging, names)
+	if err != nil {
+		return WalletBackup{}, fmt.Errorf("%s is not a wallet backup of %s: %w", opts.File, id, err)
+	}
+
+	stopped, err := stopAndWait(as, cd, cs)
+	if err != nil {
+		return WalletBackup{}, err
+	}
+
+	var replaced WalletBackup
+	if current := existingPaths(root, names); len(current) > 0 {
+		if replaced, err = writeWalletBackup(cd, "wallet-replaced", root, current, opts.Passphrase); err != nil {
+			return replaced, fmt.Errorf("could not back up the current wallet, nothing was restored: %w", err)
+		}
+		for _, p := range current {
+			println("Deleting " + p)
+			if err := os.RemoveAll(p); err != nil {
+				return replaced, fmt.Errorf("%w, the previous wallet is in %s", err, replaced.Path)
+			}
+		}
+	}
+	for _, name := range restored {
+		println("Restoring " + filepath.Join(root, name))
+		if err := os.Rename(filepath.Join(staging, name), filepath.Join(root, name)); err != nil {
+			return replaced, fmt.Errorf("%w, the previous wallet is in %s", err, replaced.Path)
+		}
+	}
+
+	return replaced, restartChains(as, stopped)
+}
+
+// restartChains starts the chains stopAndWait stopped again. Sidechains
+// are only started once drivechain answers.
+func restartChains(as *AppState, ids []string) error {
+	var errs []error
+	for i, id := range ids {
+		err := StartChainByID(as, id)
+		if err == nil && i == 0 && len(ids) > 1 {
+			_, err = waitForRunning(NewLocalController(as, true), id, restartTimeout)
+		}
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("the wallet was restored but %s did not start: %w", id, err)
+		if i == 0 && len(ids) > 1 {
+			// The sidechains can't start without drivechain
+			return fmt.Errorf("%w, start %s again by hand", err, strings.Join(ids[1:], ", "))
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// checkWalletBackup returns the files of an extracted backup, which must
+// all be wallet files of the chain.
+func checkWalletBackup(dir string, names []string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("the archive is empty")
+	}
+	wallet := make(map[string]bool, len(names))
+	for _, name := range names {
+		wallet[name] = true
+	}
+	var restored []string
+	for _, e := range entries {
+		if !wallet[e.Name()] {
+			return nil, fmt.Errorf("unexpected %s", e.Name())
+		}
+		restored = append(restored, e.Name())
+	}
+	return restored, nil
+}
+
+// Encrypted backups are the magic, a random salt and nonce, then the
+// archive sealed with AES-256-GCM. The key is derived from the passphrase
+// with PBKDF2-HMAC-SHA256.
+const (
+	encryptedMagic   = "DCLBACKUP1"
+	backupSaltSize   = 16
+	pbkdf2Iterations = 600000
+)
+
+func isEncryptedBackup(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	magic := make([]byte, len(encryptedMagic))
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return false, nil
+	}
+	return string(magic) == encryptedMagic, nil
+}
+
+func encryptBackup(src string, dst string, passphrase string) error {
+	plain, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	salt := make([]byte, backupSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	aead, err := backupCipher(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	out := append([]byte(encryptedMagic), salt...)
+	out = append(out, nonce...)
+	out = aead.Seal(out, nonce, plain, []byte(encryptedMagic))
+	return writeFileAtomic(dst, out)
+}
+
+func decryptBackup(src string, dst string, passphrase string) error {
+	b, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(string(b), encryptedMagic) {
+		return fmt.Errorf("%s is not an encrypted backup", src)
+	}
+	b = b[len(encryptedMagic):]
+	if len(b) < backupSaltSize {
+		return fmt.Errorf("%s is truncated", src)
+	}
+	aead, err := backupCipher(passphrase, b[:backupSaltSize])
+	if err != nil {
+		return err
+	}
+	b = b[backupSaltSize:]
+	if len(b) < aead.NonceSize() {
+		return fmt.Errorf("%s is truncated", src)
+	}
+	plain, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], []byte(encryptedMagic))
+	if err != nil {
+		return fmt.Errorf("%s: wrong passphrase or damaged backup", src)
+	}
+	return writeFileAtomic(dst, plain)
+}
+
+func backupCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2Key(passphrase, salt, pbkdf2Iterations))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2Key derives a 32 byte AES-256 key from passphrase with
+// PBKDF2-HMAC-SHA256.
+func pbkdf2Key(passphrase string, salt []byte, iterations int) []byte {
+	return pbkdf2.Key([]byte(passphrase), salt, iterations, 32, sha256.New)
+}
+
+// writeFileAtomic writes b next to path first, so a failed write never
+// leaves a truncated file behind.
+func writeFileAtomic(path string, b []byte) error {
+	tmp := path + ".partial"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
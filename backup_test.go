@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBackupEncryption(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "wallet.tar.gz")
+	if err := os.WriteFile(plain, []byte("wallet archive"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	enc := filepath.Join(dir, "wallet.tar.gz.enc")
+	if err := encryptBackup(plain, enc, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "wallet archive") {
+		t.Fatal("backup not encrypted")
+	}
+	if ok, err := isEncryptedBackup(enc); !ok || err != nil {
+		t.Errorf("isEncryptedBackup(encrypted) = %v, %v", ok, err)
+	}
+	if ok, err := isEncryptedBackup(plain); ok || err != nil {
+		t.Errorf("isEncryptedBackup(plain) = %v, %v", ok, err)
+	}
+
+	flipped := append([]byte(nil), b...)
+	flipped[len(flipped)-1] ^= 1
+	for _, tt := range []struct {
+		name       string
+		contents   []byte
+		passphrase string
+		ok         bool
+	}{
+		{"round trip", b, "secret", true},
+		{"wrong passphrase", b, "Secret", false},
+		{"empty passphrase", b, "", false},
+		{"damaged", flipped, "secret", false},
+		{"truncated after the salt", b[:len(encryptedMagic)+backupSaltSize+4], "secret", false},
+		{"truncated in the magic", b[:4], "secret", false},
+		{"not encrypted", []byte("wallet archive"), "secret", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			src := filepath.Join(t.TempDir(), "backup.enc")
+			if err := os.WriteFile(src, tt.contents, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			dst := filepath.Join(t.TempDir(), "backup.tar.gz")
+			err := decryptBackup(src, dst, tt.passphrase)
+			if (err == nil) != tt.ok {
+				t.Fatalf("decryptBackup = %v", err)
+			}
+			got, rerr := os.ReadFile(dst)
+			if tt.ok && string(got) != "wallet archive" {
+				t.Errorf("decrypted to %q, %v", got, rerr)
+			}
+			if !tt.ok && !os.IsNotExist(rerr) {
+				t.Errorf("%s written after a failed decrypt", dst)
+			}
+		})
+	}
+}
+
+// The first 32 bytes of the PBKDF2-HMAC-SHA256 vectors of RFC 7914
+func TestPBKDF2Key(t *testing.T) {
+	for _, tt := range []struct {
+		passphrase, salt string
+		iterations       int
+		want             string
+	}{
+		{"passwd", "salt", 1, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc"},
+		{"Password", "NaCl", 80000, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56"},
+	} {
+		if got := hex.EncodeToString(pbkdf2Key(tt.passphrase, []byte(tt.salt), tt.iterations)); got != tt.want {
+			t.Errorf("pbkdf2Key(%q, %q, %d) = %s, want %s", tt.passphrase, tt.salt, tt.iterations, got, tt.want)
+		}
+	}
+}
+
+func TestBackupWalletOfRunningThunder(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, running := range []bool{false, true} {
+		as := testAppState()
+		cd := ChainData{ID: "thunder", Kind: KindThunder, ConfDir: t.TempDir()}
+		if err := os.WriteFile(filepath.Join(cd.ConfDir, "wallet.mdb"), []byte("wallet"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		as.setChainData(&cd)
+		if running {
+			// Any live process will do
+			if _, err := as.sup.Adopt(cd.ID, os.Getpid()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		_, err := BackupWallet(as, cd.ID, BackupOptions{})
+		if (err == nil) == running {
+			t.Errorf("BackupWallet with running %v = %v", running, err)
+		}
+	}
+}
+
+func TestPromptsShareStdin(t *testing.T) {
+	t.Setenv(backupPassphraseEnv, "")
+	in := bufio.NewReader(strings.NewReader("pw\npw\ny\n"))
+	for _, prompt := range []string{"Passphrase: ", "Repeat the passphrase: "} {
+		p, err := readPassphrase(in, prompt)
+		if err != nil || p != "pw" {
+			t.Fatalf("%q = %q, %v, want pw", prompt, p, err)
+		}
+	}
+	if !confirm(in, "Continue?") {
+		t.Fatal("the answer y after the passphrases was not read")
+	}
+}
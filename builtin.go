@@ -0,0 +1,53 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strings"
+)
+
+// builtinVersion is the version the built in binary of a chain is installed
+// as.
+const builtinVersion = "builtin"
+
+// installBuiltin installs the built in binary of cd as a version of its
+// own, like a download. Reports false if the launcher has none for cd.
+func installBuiltin(cd *ChainData, as *AppState) (string, bool, error) {
+	b, format := builtinArtifact(cd.ID)
+	if len(b) == 0 {
+		return "", false, nil
+	}
+	sum := sha256.Sum256(b)
+	hash := hex.EncodeToString(sum[:])
+	m, err := loadManifest(cd)
+	if err != nil {
+		return "", true, err
+	}
+	if iv, ok := m.find(builtinVersion); ok && strings.EqualFold(iv.SHA256, hash) {
+		if _, err := os.Stat(versionDir(cd, builtinVersion)); err == nil {
+			return builtinVersion, true, nil
+		}
+	}
+	if err := checkReplaceable(cd, as, m, builtinVersion); err != nil {
+		return "", true, err
+	}
+
+	println("Installing the built in " + cd.ID + " binary")
+	f, err := os.CreateTemp(cd.ConfDir, ".builtin-")
+	if err != nil {
+		return "", true, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", true, err
+	}
+	err = addVersion(cd, builtinVersion, hash, false, func(dir string) error {
+		return installArtifact(cd, Artifact{Format: string(format)}, f.Name(), dir)
+	})
+	return builtinVersion, true, err
+}
@@ -0,0 +1,55 @@
+//go:build builtin
+
+package main
+
+import (
+	_ "embed"
+	"runtime"
+
+	"dc-launcher/archive"
+)
+
+// Binaries built into launchers built with -tags builtin, installed for
+// chains the catalogue has no download of for the platform. The binaries
+// are not in the repository, put them in binaries/linux first.
+
+//go:embed binaries/linux/drivechain-qt-linux
+var drivechainLinux []byte
+
+//go:embed binaries/linux/testchain-qt-linux
+var testchainLinux []byte
+
+//go:embed binaries/linux/bitassets-qt-linux
+var bitassetsLinux []byte
+
+//go:embed binaries/linux/thunder-linux
+var thunderLinux []byte
+
+//go:embed binaries/linux/bitcoin-qt-linux
+var latestCoreLinux []byte
+
+//go:embed binaries/linux/bitnames.zip
+var bitnamesZipLinux []byte
+
+// builtinArtifact returns the binary built into the launcher for chain id
+// and its archive format, nil if there is none for this platform.
+func builtinArtifact(id string) ([]byte, archive.Format) {
+	if runtime.GOOS != "linux" {
+		return nil, ""
+	}
+	switch id {
+	case "drivechain":
+		return drivechainLinux, ""
+	case "testchain":
+		return testchainLinux, ""
+	case "bitassets":
+		return bitassetsLinux, ""
+	case "thunder":
+		return thunderLinux, ""
+	case "latestcore":
+		return latestCoreLinux, ""
+	case "bitnames":
+		return bitnamesZipLinux, archive.Zip
+	}
+	return nil, ""
+}
@@ -0,0 +1,11 @@
+//go:build !builtin
+
+package main
+
+import "dc-launcher/archive"
+
+// builtinArtifact returns nil, binaries are only built into launchers built
+// with -tags builtin.
+func builtinArtifact(id string) ([]byte, archive.Format) {
+	return nil, ""
+}
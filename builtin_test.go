@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInstallBuiltin(t *testing.T) {
+	if b, _ := builtinArtifact("drivechain"); len(b) == 0 {
+		t.Skip("binaries are only built in on linux with -tags builtin")
+	}
+	for _, tt := range []struct {
+		id   string
+		kind ChainKind
+		ok   bool
+	}{
+		{"drivechain", KindDrivechain, true},
+		{"bitnames", KindBitnames, true},
+		{"mychain", KindBitcoinSidechain, false},
+	} {
+		t.Run(tt.id, func(t *testing.T) {
+			as := NewAppState()
+			cd := &ChainData{ID: tt.id, Kind: tt.kind, BinName: tt.id, ConfDir: t.TempDir()}
+			version, ok, err := installBuiltin(cd, as)
+			if err != nil || ok != tt.ok {
+				t.Fatalf("installBuiltin = %q, %v, %v", version, ok, err)
+			}
+			if !tt.ok {
+				return
+			}
+			m, err := loadManifest(cd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			first, found := m.find(builtinVersion)
+			if !found || len(first.Files) == 0 {
+				t.Fatalf("manifest = %+v", m)
+			}
+			for name := range first.Files {
+				if _, err := os.Stat(filepath.Join(versionDir(cd, version), name)); err != nil {
+					t.Error(err)
+				}
+			}
+
+			// Installed once only
+			if _, _, err := installBuiltin(cd, as); err != nil {
+				t.Fatal(err)
+			}
+			m, _ = loadManifest(cd)
+			if again, _ := m.find(builtinVersion); !again.InstalledAt.Equal(first.InstalledAt) {
+				t.Error("installed again")
+			}
+		})
+	}
+}
+
+func TestEnsureInstalledWithoutArtifact(t *testing.T) {
+	if b, _ := builtinArtifact("drivechain"); len(b) > 0 {
+		t.Skip("the launcher has binaries built in")
+	}
+	dir := t.TempDir()
+	cd := &ChainData{ID: "drivechain", Kind: KindDrivechain, BinName: "drivechain-qt-linux", ConfDir: dir, BinDir: dir}
+	err := EnsureInstalled(cd, NewAppState())
+	var le *LaunchError
+	if !errors.As(err, &le) || le.Kind != BinaryMissing {
+		t.Fatalf("EnsureInstalled = %v, want the binary missing", err)
+	}
+}
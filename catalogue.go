@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+const (
+	catalogueCacheName = "catalogue.json"
+	overridesName      = "overrides.json"
+	catalogueTimeout   = 10 * time.Second
+	maxCatalogueSize   = 1 << 20
+)
+
+// releaseCatalogueKey is the base64 ed25519 key the project signs its
+// catalogue with. Release builds set it with
+// -ldflags "-X main.releaseCatalogueKey=<key>", without it remote
+// catalogues need unofficialCatalogue.
+var releaseCatalogueKey = ""
+
+// LoadCatalogue returns the chain providers: the remote catalogue if one is
+// configured and its signature checks out, else the last verified copy, else
+// the catalogue built into the launcher. User overrides from overrides.json
+// are merged on top. Default ports are moved by the profile's port offset
+// unless overridden.
+func LoadCatalogue(dir string) (map[string]ChainProvider, error) {
+	settings, err := LoadSettings()
+	if err != nil {
+		println(err.Error())
+	}
+
+	catalogue := chainsBytes
+	if settings.CatalogueURL != "" {
+		if b, err := updateCatalogue(dir, settings); err == nil {
+			catalogue = b
+		} else {
+			println("Using built in chain catalogue: " + err.Error())
+		}
+	}
+
+	if err := migrateChainsJSON(dir); err != nil {
+		println(err.Error())
+	}
+
+	var merged map[string]interface{}
+	if err := json.Unmarshal(catalogue, &merged); err != nil {
+		return nil, fmt.Errorf("chain catalogue: %w", err)
+	}
+	var o map[string]interface{}
+	overrides, err := os.ReadFile(filepath.Join(dir, overridesName))
+	if err == nil {
+		if err := json.Unmarshal(overrides, &o); err != nil {
+			return nil, fmt.Errorf("%s: %w", overridesName, err)
+		}
+		mergeJSON(merged, o)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	b, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	var providers map[string]ChainProvider
+	if err := json.Unmarshal(b, &providers); err != nil {
+		return nil, err
+	}
+	for id, cp := range providers {
+		fields, _ := o[id].(map[string]interface{})
+		cp.Overridden = make(map[string]bool, len(fields))
+		for field := range fields {
+			cp.Overridden[field] = true
+		}
+		if !cp.Overridden["defaultPort"] {
+			cp.DefaultPort += settings.PortOffset
+		}
+		providers[id] = cp
+	}
+	return providers, nil
+}
+
+// updateCatalogue fetches the configured catalogue and caches it once its
+// signature is verified. If fetching fails the cached copy is used, its
+// signature is checked again in case the file was modified.
+func updateCatalogue(dir string, settings LauncherSettings) ([]byte, error) {
+	key, err := cataloguePublicKey(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := filepath.Join(dir, catalogueCacheName)
+	catalogue, sig, err := fetchCatalogue(settings.CatalogueURL)
+	if err == nil {
+		err = verifyCatalogue(key, catalogue, sig)
+	}
+	if err == nil {
+		if err := os.WriteFile(cache, catalogue, 0o644); err != nil {
+			println(err.Error())
+		}
+		if err := os.WriteFile(cache+".sig", sig, 0o644); err != nil {
+			println(err.Error())
+		}
+		return catalogue, nil
+	}
+	println("Could not update chain catalogue: " + err.Error())
+
+	catalogue, cacheErr := os.ReadFile(cache)
+	if cacheErr != nil {
+		return nil, err
+	}
+	sig, cacheErr = os.ReadFile(cache + ".sig")
+	if cacheErr != nil {
+		return nil, err
+	}
+	if err := verifyCatalogue(key, catalogue, sig); err != nil {
+		return nil, fmt.Errorf("cached catalogue: %w", err)
+	}
+	return catalogue, nil
+}
+
+// cataloguePublicKey returns the key the catalogue must be signed with. The
+// key in launcher.json is only trusted for catalogues the user marked as
+// unofficial, anyone able to write launcher.json could swap it otherwise.
+func cataloguePublicKey(settings LauncherSettings) (ed25519.PublicKey, error) {
+	k := releaseCatalogueKey
+	switch {
+	case settings.UnofficialCatalogue:
+		if settings.CatalogueKey == "" {
+			return nil, errors.New("unofficialCatalogue needs a catalogueKey, refusing unsigned catalogue")
+		}
+		println("Using unofficial chain catalogue signed by " + settings.CatalogueKey)
+		k = settings.CatalogueKey
+	case settings.CatalogueKey != "":
+		println("Ignoring catalogueKey, set unofficialCatalogue to use a catalogue not signed by the release key")
+	}
+	if k == "" {
+		return nil, errors.New("this build has no release catalogue key, set unofficialCatalogue and catalogueKey to use another catalogue")
+	}
+	key, err := parsePublicKey(k)
+	if err != nil {
+		return nil, fmt.Errorf("catalogue key: %w", err)
+	}
+	return key, nil
+}
+
+// fetchCatalogue downloads the catalogue and its detached signature, which
+// is expected next to it with a .sig suffix.
+func fetchCatalogue(url string) ([]byte, []byte, error) {
+	catalogue, err := httpGet(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	sig, err := httpGet(url + ".sig")
+	if err != nil {
+		return nil, nil, err
+	}
+	return catalogue, sig, nil
+}
+
+func httpGet(url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), catalogueTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxCatalogueSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxCatalogueSize {
+		return nil, fmt.Errorf("GET %s: response too large", url)
+	}
+	return b, nil
+}
+
+// verifyCatalogue checks sig, a base64 ed25519 signature over the raw
+// catalogue bytes, and that the catalogue parses.
+func verifyCatalogue(key ed25519.PublicKey, catalogue []byte, sig []byte) error {
+	s, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(sig)))
+	if err != nil {
+		return fmt.Errorf("catalogue signature: %w", err)
+	}
+	if !ed25519.Verify(key, catalogue, s) {
+		return errors.New("catalogue signature does not match")
+	}
+	var providers map[string]ChainProvider
+	if err := json.Unmarshal(catalogue, &providers); err != nil {
+		return fmt.Errorf("catalogue: %w", err)
+	}
+	return nil
+}
+
+// mergeJSON merges src into dst field by field. Objects are merged
+// recursively, anything else replaces the value in dst and null removes it,
+// e.g. {"thunder": null} hides thunder.
+func mergeJSON(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if v == nil {
+			delete(dst, k)
+			continue
+		}
+		sv, srcObj := v.(map[string]interface{})
+		dv, dstObj := dst[k].(map[string]interface{})
+		if srcObj && dstObj {
+			mergeJSON(dv, sv)
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// diffJSON returns the fields of cur that differ from base, the inverse of
+// mergeJSON.
+func diffJSON(base, cur map[string]interface{}) map[string]interface{} {
+	diff := make(map[string]interface{})
+	for k, v := range cur {
+		bv, ok := base[k]
+		if !ok {
+			diff[k] = v
+			continue
+		}
+		cv, curObj := v.(map[string]interface{})
+		bo, baseObj := bv.(map[string]interface{})
+		if curObj && baseObj {
+			if d := diffJSON(bo, cv); len(d) > 0 {
+				diff[k] = d
+			}
+			continue
+		}
+		if !reflect.DeepEqual(bv, v) {
+			diff[k] = v
+		}
+	}
+	return diff
+}
+
+// migrateChainsJSON turns the full chains.json copy older launchers wrote
+// into overrides holding only what the user changed, so catalogue updates
+// are not shadowed by a stale copy.
+func migrateChainsJSON(dir string) error {
+	legacy := filepath.Join(dir, defaultChainProvidersConfName)
+	b, err := os.ReadFile(legacy)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var base, cur map[string]interface{}
+	if err := json.Unmarshal(chainsBytes, &base); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, &cur); err != nil {
+		return fmt.Errorf("%s: %w", legacy, err)
+	}
+	diff := diffJSON(base, cur)
+
+	overrides := filepath.Join(dir, overridesName)
+	if _, err := os.Stat(overrides); os.IsNotExist(err) && len(diff) > 0 {
+		out, err := json.MarshalIndent(diff, "", "    ")
+		if err != nil {
+			return err
+		}
+		println("Moving changes in " + legacy + " to " + overrides)
+		if err := os.WriteFile(overrides, append(out, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+	return os.Rename(legacy, legacy+".bak")
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func decodeJSON(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		t.Fatalf("%s: %v", s, err)
+	}
+	return m
+}
+
+func TestMergeJSON(t *testing.T) {
+	base := `{"testchain": {"name": "Testchain", "defaultPort": 19000, "launch": {"args": ["-a"]}}, "thunder": {"name": "Thunder"}}`
+	tests := []struct {
+		name      string
+		overrides string
+		want      string
+	}{
+		{
+			name:      "field replaced",
+			overrides: `{"testchain": {"defaultPort": 19100}}`,
+			want:      `{"testchain": {"name": "Testchain", "defaultPort": 19100, "launch": {"args": ["-a"]}}, "thunder": {"name": "Thunder"}}`,
+		},
+		{
+			name:      "arrays replaced, not merged",
+			overrides: `{"testchain": {"launch": {"args": ["-b"]}}}`,
+			want:      `{"testchain": {"name": "Testchain", "defaultPort": 19000, "launch": {"args": ["-b"]}}, "thunder": {"name": "Thunder"}}`,
+		},
+		{
+			name:      "null hides a chain",
+			overrides: `{"thunder": null}`,
+			want:      `{"testchain": {"name": "Testchain", "defaultPort": 19000, "launch": {"args": ["-a"]}}}`,
+		},
+		{
+			name:      "new chain added",
+			overrides: `{"mychain": {"name": "Mine"}}`,
+			want:      `{"testchain": {"name": "Testchain", "defaultPort": 19000, "launch": {"args": ["-a"]}}, "thunder": {"name": "Thunder"}, "mychain": {"name": "Mine"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := decodeJSON(t, base)
+			mergeJSON(got, decodeJSON(t, tt.overrides))
+			if want := decodeJSON(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("merged = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDiffJSONInvertsMerge(t *testing.T) {
+	base := decodeJSON(t, `{"testchain": {"name": "Testchain", "defaultPort": 19000, "launch": {"args": ["-a"]}}}`)
+	cur := decodeJSON(t, `{"testchain": {"name": "Testchain", "defaultPort": 19100, "launch": {"args": ["-a"]}}, "mychain": {"name": "Mine"}}`)
+
+	diff := diffJSON(base, cur)
+	want := decodeJSON(t, `{"testchain": {"defaultPort": 19100}, "mychain": {"name": "Mine"}}`)
+	if !reflect.DeepEqual(diff, want) {
+		t.Fatalf("diffJSON = %v, want %v", diff, want)
+	}
+	mergeJSON(base, diff)
+	if !reflect.DeepEqual(base, cur) {
+		t.Errorf("base merged with the diff = %v, want %v", base, cur)
+	}
+}
+
+func TestVerifyCatalogue(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	catalogue := []byte(`{"testchain": {"name": "Testchain"}}`)
+	sign := func(b []byte) []byte {
+		return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, b)) + "\n")
+	}
+
+	tests := []struct {
+		name      string
+		key       ed25519.PublicKey
+		catalogue []byte
+		sig       []byte
+		ok        bool
+	}{
+		{"valid", pub, catalogue, sign(catalogue), true},
+		{"other key", otherPub, catalogue, sign(catalogue), false},
+		{"modified", pub, []byte(`{"testchain": {"name": "Evil"}}`), sign(catalogue), false},
+		{"not base64", pub, catalogue, []byte("not a signature"), false},
+		{"signed but not json", pub, []byte("nope"), sign([]byte("nope")), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyCatalogue(tt.key, tt.catalogue, tt.sig)
+			if (err == nil) != tt.ok {
+				t.Errorf("verifyCatalogue = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCataloguePublicKey(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := base64.StdEncoding.EncodeToString(pub)
+	// nil while the release has no key
+	release, _ := parsePublicKey(releaseCatalogueKey)
+
+	tests := []struct {
+		name     string
+		settings LauncherSettings
+		want     ed25519.PublicKey
+	}{
+		{"unofficial", LauncherSettings{CatalogueKey: key, UnofficialCatalogue: true}, pub},
+		{"unofficial without key", LauncherSettings{UnofficialCatalogue: true}, nil},
+		{"unofficial with a bad key", LauncherSettings{CatalogueKey: "short", UnofficialCatalogue: true}, nil},
+		// A key in launcher.json alone is not trusted
+		{"key without opt in", LauncherSettings{CatalogueKey: key}, release},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cataloguePublicKey(tt.settings)
+			if (err == nil) != (tt.want != nil) {
+				t.Fatalf("cataloguePublicKey = %v, %v", got, err)
+			}
+			if tt.want != nil && !got.Equal(tt.want) {
+				t.Errorf("cataloguePublicKey = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReleaseCatalogueKey(t *testing.T) {
+	if releaseCatalogueKey == "" {
+		t.Skip("no release key, test it with -ldflags \"-X dc-launcher.releaseCatalogueKey=<key>\"")
+	}
+	key, err := parsePublicKey(releaseCatalogueKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		t.Fatalf("releaseCatalogueKey %q: %v", releaseCatalogueKey, err)
+	}
+}
+
+func TestUpdateCatalogueSignedByRelease(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPriv, _ := ed25519.GenerateKey(rand.Reader)
+	release := releaseCatalogueKey
+	releaseCatalogueKey = base64.StdEncoding.EncodeToString(pub)
+	t.Cleanup(func() { releaseCatalogueKey = release })
+
+	catalogue := []byte(`{"testchain": {"name": "Testchain"}}`)
+	for _, tt := range []struct {
+		name   string
+		signer ed25519.PrivateKey
+		ok     bool
+	}{
+		{"release key", priv, true},
+		{"other key", otherPriv, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			sig := base64.StdEncoding.EncodeToString(ed25519.Sign(tt.signer, catalogue))
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/chains.json":
+					w.Write(catalogue)
+				case "/chains.json.sig":
+					w.Write([]byte(sig))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer srv.Close()
+
+			got, err := updateCatalogue(t.TempDir(), LauncherSettings{CatalogueURL: srv.URL + "/chains.json"})
+			if (err == nil) != tt.ok {
+				t.Fatalf("updateCatalogue = %v, want ok %v", err, tt.ok)
+			}
+			if tt.ok && string(got) != string(catalogue) {
+				t.Errorf("updateCatalogue = %s", got)
+			}
+		})
+	}
+}
+
+func TestProfileSidechainsUseTheirDrivechain(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	s, err := CreateProfile("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.PortOffset == 0 {
+		t.Fatal("named profile without a port offset")
+	}
+	if err := SetProfile("demo"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetProfile("") })
+	checkMainchainPort(t, 18443+s.PortOffset)
+}
@@ -1,43 +1,79 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
-	"github.com/biter777/processex"
+	"dc-launcher/rpc"
 )
 
 type ChainProvider struct {
-	ID              string `json:"id"`
-	Name            string `json:"name"`
-	Description     string `json:"description"`
-	RepoURL         string `json:"repoUrl"`
-	ImageURL        string `json:"imageUrl"`
-	BinName         string `json:"binName"`
-	DefaultDir      string `json:"defaultDir"`
-	DefaultConfName string `json:"defaultConfName"`
-	DefaultPort     int    `json:"defaultPort"`
-	DefaultSlot     int    `json:"defaultSlot,omitempty"`
+	ID              string    `json:"id"`
+	Kind            ChainKind `json:"kind,omitempty"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description"`
+	RepoURL         string    `json:"repoUrl"`
+	ImageURL        string    `json:"imageUrl"`
+	BinName         string    `json:"binName"`
+	DefaultDir      string    `json:"defaultDir"`
+	DefaultConfName string    `json:"defaultConfName"`
+	DefaultPort     int       `json:"defaultPort"`
+	DefaultSlot     int       `json:"defaultSlot,omitempty"`
+	DefaultP2PPort  int       `json:"defaultP2PPort,omitempty"` // Bitcoin Core's for drivechain and latestcore if 0
+	Version         string    `json:"version,omitempty"`        // Version of the artifacts
+	StopTimeout     int       `json:"stopTimeout,omitempty"`    // Seconds, defaults to defaultStopTimeout
+
+	Restart RestartPolicy   `json:"restart,omitempty"`
+	Launch  *LaunchTemplate `json:"launch,omitempty"` // Defaults to the command line of the kind's driver
+
+	// Downloads by platform, e.g. linux-amd64
+	Artifacts map[string]Artifact `json:"artifacts,omitempty"`
+	// Base64 ed25519 key the artifacts must be signed with
+	SigningKey string `json:"signingKey,omitempty"`
+
+	// Fields set in overrides.json
+	Overridden map[string]bool `json:"-"`
 }
 
 type ChainData struct {
-	ID           string `json:"id"`
-	IsDrivechain bool   `json:"isdrivechain,omitempty"`
-	BinDir       string `json:"bindir,omitempty"`
-	BinName      string `json:"binname,omitempty"`
-	ConfDir      string `json:"confdir,omitempty"`
-	ConfName     string `json:"confname,omitempty"`
-	Port         int    `json:"rpcport"`
-	RPCUser      string `json:"rpcuser"`
-	RPCPass      string `json:"rpcpassword"`
-	Slot         int    `json:"slot,omitempty"`       // Only apply to sidechains
-	RefreshBMM   bool   `json:"refreshbmm,omitempty"` // Only apply to sidechains
-	BMMFee       bool   `json:"bmmfee,omitempty"`     // Only apply to sidechains
+	ID           string    `json:"id"`
+	Kind         ChainKind `json:"-"`
+	IsDrivechain bool      `json:"isdrivechain,omitempty"`
+	BinDir       string    `json:"bindir,omitempty"`
+	InstallDir   string    `json:"-"` // Active version, the data directory for hand installed binaries
+	BinName      string    `json:"binname,omitempty"`
+	ConfDir      string    `json:"confdir,omitempty"`
+	ConfName     string    `json:"confname,omitempty"`
+	Port         int       `json:"rpcport"`
+	P2PPort      int       `json:"port,omitempty"` // 0 if unknown
+	Network      string    `json:"-"`              // main, test, testnet4, signet or regtest
+	NetDir       string    `json:"-"`              // Where the chain keeps the network's data, e.g. <datadir>/regtest
+	RPCUser      string    `json:"rpcuser"`
+	RPCPass      string    `json:"rpcpassword"`
+	CookieFile   string    `json:"-"` // Used if there is no rpcpassword
+	RPCHost      string    `json:"rpcconnect,omitempty"`
+	Slot         int       `json:"slot,omitempty"`       // Only apply to sidechains
+	RefreshBMM   bool      `json:"refreshbmm,omitempty"` // Only apply to sidechains
+	BMMFee       bool      `json:"bmmfee,omitempty"`     // Only apply to sidechains
+
+	StopTimeout time.Duration       `json:"-"` // How long to wait for each stop step
+	Restart     RestartPolicy       `json:"-"`
+	Launch      *LaunchTemplate     `json:"-"`
+	Artifacts   map[string]Artifact `json:"-"`
+	Version     string              `json:"-"` // Catalogue version of the artifacts
+	SigningKey  string              `json:"-"`
+	PortSource  ValueSource         `json:"-"`
+	SlotSource  ValueSource         `json:"-"`
+	P2PSource   ValueSource         `json:"-"`
 }
 
 type ChainState struct {
@@ -46,11 +82,18 @@ type ChainState struct {
 	AvailableBalance float64 `json:"availablebalance"`
 	PendingBalance   float64 `json:"pendingbalance"`
 	Height           int     `json:"height,omitempty"`
+	MempoolSize      int     `json:"mempoolsize"`
+	BestBlockHash    string  `json:"bestblockhash,omitempty"`
 	Slot             int     `json:"slot,omitempty"` // Only apply to sidechains
 	Automine         bool    `json:"automine,omitempty"`
+	CrashCount       int     `json:"crashcount"`
+	LastExitCode     int     `json:"lastexitcode"`
+	RestartAttempts  int     `json:"restartattempts,omitempty"`
 	ChainStateUpdate ChainStateUpdate
 }
 
+const defaultStopTimeout = 30 * time.Second
+
 type State uint
 
 const (
@@ -59,260 +102,450 @@ const (
 	Running
 )
 
+func (s State) String() string {
+	switch s {
+	case Waiting:
+		return "waiting"
+	case Running:
+		return "running"
+	}
+	return "unknown"
+}
+
 type ChainStateUpdate struct {
 	ID    string `json:"id"`
 	timer *time.Ticker
 	quit  chan struct{}
 }
 
-func getChainProcess(name string) (*os.Process, error) {
-	process, _, err := processex.FindByName(name)
-	if err == processex.ErrNotFound {
-		return nil, err
+// LaunchChain starts the chain binary under the supervisor. Problems that
+// prevent the chain from starting are returned as a *LaunchError.
+func LaunchChain(cd *ChainData, cs *ChainState, as *AppState) error {
+	if as.sup.IsRunning(cd.ID) {
+		println(cd.BinName + " already running...")
+		return nil
 	}
-	if err != nil {
-		return nil, err
+
+	if err := EnsureInstalled(cd, as); err != nil {
+		return err
 	}
-	if len(process) > 0 {
-		return process[0], nil
+	if err := checkInstall(cd); err != nil {
+		return err
 	}
-	return nil, fmt.Errorf("something went wrong finding process")
-}
 
-func LaunchChain(cd *ChainData, cs *ChainState, mui *MainUI) {
-	if cs.ChainStateUpdate.timer != nil && cs.ChainStateUpdate.quit != nil {
-		// TODO: Maybe restart?
-	} else {
-		// TODO: Thunder needs rpc
-		if cd.ID != "thunder" {
-			csu := ChainStateUpdate{ID: cd.ID}
-			cs.ChainStateUpdate = csu
-			StartChainStateUpdate(cd, cs, mui)
-		}
+	drv := cd.Driver()
+	lc, err := launchCommand(cd, as)
+	if err != nil {
+		return err
+	}
+	executable := lc.Exec
+
+	if err := PreflightChain(as, cd, executable); err != nil {
+		return err
 	}
 
-	//p, err := getChainProcess(cd.BinName)
-	//if p != nil && err == nil {
-	//println(cd.BinName + " already running...")
-	// TODO: Try kill?
-	// Seems that running form launcher, when you stop chain with rpc stop method, the process stays
-	// alive until you close the launcher.  If you close the launcher, the process dies.
-	//return
-	//}
-
-	if cd.ID == "thunder" {
-
-		dataDir := cd.ConfDir
-		netAddr := fmt.Sprintf("127.0.0.1:%v", cd.Port)
-		dcAddr := fmt.Sprintf("127.0.0.1:%v", mui.as.dcd.Port)
-		args := []string{"-d", dataDir, "-n", netAddr, "-m", dcAddr, "-u", mui.as.dcd.RPCUser, "-p", mui.as.dcd.RPCPass}
-		cmd := exec.Command(cd.BinDir+string(os.PathSeparator)+cd.BinName, args...)
-		err := cmd.Start()
-		if err != nil {
-			log.Fatal(err)
+	var logFile *os.File
+	status, err := as.sup.Start(cd.ID, func() (*exec.Cmd, error) {
+		cmd := exec.Command(executable, lc.Args...)
+		cmd.Dir = lc.WorkDir
+		if len(lc.Env) > 0 {
+			cmd.Env = append(os.Environ(), lc.Env...)
 		}
-		cs.State = Running
-		mui.as.scs[cd.ID] = *cs
-		mui.Refresh()
-
-	} else {
-		if cd.ID == "bitnames" {
-			args := []string{}
-			cmd := exec.Command(cd.ConfDir+string(os.PathSeparator)+"start.sh", args...)
-			cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Foreground: true}
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
-			err := cmd.Start()
-			if err != nil {
-				log.Fatal(err)
-			}
-		} else {
-			args := []string{"-conf=" + cd.ConfDir + string(os.PathSeparator) + cd.ConfName}
-			cmd := exec.Command(cd.BinDir+string(os.PathSeparator)+cd.BinName, args...)
-			cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Foreground: true}
-			cmd.Stdout = os.Stdout
-			err := cmd.Start()
+		// Own process group so the whole chain can be signalled at once
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		if as.detach {
+			// The chain outlives this process, don't tie it to our stdout
+			f, err := os.OpenFile(chainLogFile(cd), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
 			if err != nil {
-				log.Fatal(err)
+				return nil, err
 			}
-			cs.State = Waiting
+			logFile = f
+			cmd.Stdout = f
+			cmd.Stderr = f
+			return cmd, nil
 		}
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd, nil
+	})
+	if logFile != nil {
+		// The child has its own copy
+		logFile.Close()
+	}
+	if err != nil {
+		return launchErrorFromStart(cd, executable, err)
 	}
+	writePIDFile(cd, status.PID)
 
-	if cd.ID == "latestcore" {
-		d := cd.ConfDir + string(os.PathSeparator) + "regtest" + string(os.PathSeparator) + "wallets"
-		empty, err := IsDirEmpty(d)
-		if empty || err != nil {
-			time.AfterFunc(time.Duration(1)*time.Second, func() {
-				LatestCoreCreateWallet(mui.as, cd, cs)
-			})
-		}
+	if drv.SupportsRPC() {
+		cs.State = Waiting
 	}
+	watchChain(cd, cs, as)
+	drv.PostStart(cd, cs, as)
 
 	println(cd.BinName + " Started...")
+	return nil
+}
+
+// chainPIDFile is where the pid of a running chain is kept so launcher
+// processes started later, e.g. the CLI, can find it.
+func chainPIDFile(cd *ChainData) string {
+	return filepath.Join(cd.ConfDir, "dclauncher.pid")
+}
+
+// chainLogFile receives the output of chains started detached.
+func chainLogFile(cd *ChainData) string {
+	return filepath.Join(cd.ConfDir, "dclauncher.log")
+}
+
+func writePIDFile(cd *ChainData, pid int) {
+	err := os.WriteFile(chainPIDFile(cd), []byte(strconv.Itoa(pid)+"\n"), 0o644)
+	if err != nil {
+		println(err.Error())
+	}
 }
 
-func StopChain(cd *ChainData, cs *ChainState, as *AppState) error {
-	if cd.ID == "drivechain" {
-		// stop all
-		for k := range as.scd {
-			scd := as.scd[k]
-			scs := as.scs[k]
-			StopChain(&scd, &scs, as)
+// watchChain starts polling the chain state if it is not polled yet.
+func watchChain(cd *ChainData, cs *ChainState, as *AppState) {
+	if !cd.Driver().SupportsRPC() {
+		// Nothing to poll, running as long as the process is
+		cs.State = Running
+		as.setPolledState(cd.ID, *cs)
+		as.Refresh()
+		return
+	}
+	if cs.ChainStateUpdate.timer == nil || cs.ChainStateUpdate.quit == nil {
+		csu := ChainStateUpdate{ID: cd.ID}
+		cs.ChainStateUpdate = csu
+		StartChainStateUpdate(cd, cs, as)
+	}
+	as.setPolledState(cd.ID, *cs)
+}
+
+// AdoptRunningChains hands chains started by an earlier launcher process to
+// the supervisor, using their pid files. If watch is set their state is
+// polled like for chains launched by this process.
+func AdoptRunningChains(as *AppState, watch bool) {
+	chains := append([]ChainData{as.drivechainData()}, as.sidechains()...)
+	for i := range chains {
+		cd := &chains[i]
+		if cd.ID == "" || as.sup.IsRunning(cd.ID) {
+			continue
+		}
+		pid, ok := runningPID(cd)
+		if !ok {
+			continue
+		}
+		if _, err := as.sup.Adopt(cd.ID, pid); err != nil {
+			println(err.Error())
+			continue
+		}
+		if !watch {
+			continue
+		}
+		cs, _ := as.chainState(cd.ID)
+		watchChain(cd, &cs, as)
+	}
+}
+
+// runningPID returns the pid of the chain from its pid file if it is still
+// running, removing a stale pid file.
+func runningPID(cd *ChainData) (int, bool) {
+	b, err := os.ReadFile(chainPIDFile(cd))
+	if err != nil {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil || !isChainProcess(cd, pid) {
+		os.Remove(chainPIDFile(cd))
+		return 0, false
+	}
+	return pid, true
+}
+
+// isChainProcess guards against the pid having been reused by an unrelated
+// process since the pid file was written.
+func isChainProcess(cd *ChainData, pid int) bool {
+	cmdline, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(cmdline), cd.BinName) || strings.Contains(string(cmdline), cd.ConfDir)
+}
+
+// StopProgress receives a short description of each step of a stop.
+type StopProgress func(step string)
+
+// StopChain shuts a chain down in escalating steps: RPC stop, SIGTERM to the
+// process group and finally SIGKILL, waiting cd.StopTimeout for the process
+// to exit after each of the first two.
+func StopChain(cd *ChainData, cs *ChainState, as *AppState, progress StopProgress) error {
+	report := func(step string) {
+		println(step)
+		if progress != nil {
+			progress(step)
 		}
 	}
 
-	if cd.ID != "thunder" {
-		req, err := MakeRpcRequest(cd, "stop", []interface{}{})
-		if err == nil {
-			defer req.Body.Close()
+	if cd.IsDrivechain {
+		// Sidechains depend on drivechain, stop them all first
+		var wg sync.WaitGroup
+		for _, scd := range as.sidechains() {
+			scd := scd
+			scs, _ := as.sidechainState(scd.ID)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := StopChain(&scd, &scs, as, progress); err != nil {
+					println(err.Error())
+				}
+			}()
 		}
+		wg.Wait()
+	}
+
+	timeout := cd.StopTimeout
+	if timeout <= 0 {
+		timeout = defaultStopTimeout
 	}
+	supervised := as.sup.IsRunning(cd.ID)
 
-	p, err := getChainProcess(cd.BinName)
-	if p != nil && err == nil {
-		if cd.ID == "thunder" {
-			cs.State = Unknown
-			mui.as.scs[cd.ID] = *cs
-			mui.Refresh()
+	drv := cd.Driver()
+	if drv.SupportsRPC() {
+		report(fmt.Sprintf("Asking %s to stop...", cd.ID))
+	}
+	as.sup.ExpectExit(cd.ID)
+	err := drv.Stop(cd)
+	switch {
+	case errors.Is(err, errNoGracefulStop):
+		// Straight to the signals
+	case err != nil:
+		println(err.Error())
+	case supervised:
+		report(fmt.Sprintf("Waiting up to %v for %s to shut down...", timeout, cd.ID))
+		if as.sup.Wait(cd.ID, timeout) {
+			report(fmt.Sprintf("%s stopped", cd.ID))
+			return nil
 		}
-		return p.Kill()
+	}
+
+	if !supervised {
+		return nil
+	}
+
+	report(fmt.Sprintf("Sending SIGTERM to %s...", cd.ID))
+	err = as.sup.Signal(cd.ID, syscall.SIGTERM)
+	if err == ErrNotRunning {
+		report(fmt.Sprintf("%s stopped", cd.ID))
+		return nil
+	}
+	if err != nil {
+		println(err.Error())
+	}
+	if as.sup.Wait(cd.ID, timeout) {
+		report(fmt.Sprintf("%s stopped", cd.ID))
+		return nil
+	}
+
+	report(fmt.Sprintf("%s did not exit after %v, sending SIGKILL...", cd.ID, timeout))
+	err = as.sup.Kill(cd.ID)
+	if err == ErrNotRunning {
+		err = nil
+	}
+	if err == nil {
+		report(fmt.Sprintf("%s killed", cd.ID))
 	}
 	return err
 }
 
-func StartChainStateUpdate(cd *ChainData, cs *ChainState, mui *MainUI) {
+// ChainExited is called by the supervisor once a chain process was reaped.
+// It records the exit in the chain state and relaunches the chain if its
+// restart policy asks for it.
+func ChainExited(as *AppState, status ProcessStatus, expected bool) {
+	cd, ok := as.chainData(status.ID)
+	if !ok {
+		return
+	}
+	cs, _ := as.chainState(status.ID)
+
+	cs.State = Unknown
+	os.Remove(chainPIDFile(&cd))
+	delay, restart := scheduleRestart(&cd, &cs, status, expected)
+	as.setChainState(status.ID, cs)
+	as.Refresh()
+
+	if restart {
+		exit := fmt.Sprintf("exited with code %d", status.ExitCode)
+		if status.ExitUnknown {
+			exit = "exited"
+		}
+		println(fmt.Sprintf("%s %s, restarting in %v (attempt %d)", cd.ID, exit, delay, cs.RestartAttempts))
+		time.AfterFunc(delay, func() {
+			if as.sup.IsRunning(cd.ID) {
+				return
+			}
+			cd, _ := as.chainData(cd.ID)
+			cs, _ := as.chainState(cd.ID)
+			if err := LaunchChain(&cd, &cs, as); err != nil {
+				println(err.Error())
+			}
+		})
+	}
+}
+
+func StartChainStateUpdate(cd *ChainData, cs *ChainState, as *AppState) {
 	cs.ChainStateUpdate.timer = time.NewTicker(1 * time.Second)
 	cs.ChainStateUpdate.quit = make(chan struct{})
+	csu, id := cs.ChainStateUpdate, cd.ID
 	go func() {
 		for {
 			select {
-			case <-cs.ChainStateUpdate.timer.C:
-				if cd.ID == "drivechain" && cs.Automine {
-					DrivechainMine(mui.as, mui)
+			case <-csu.timer.C:
+				// Work on the shared copy so bookkeeping done elsewhere, e.g.
+				// crash counts, is not overwritten
+				cur, ok := as.chainState(id)
+				if !ok {
+					continue
+				}
+				cur.ChainStateUpdate = csu
+				if cd.IsDrivechain && cur.Automine {
+					if err := DrivechainMine(as, 1); err != nil {
+						println(err.Error())
+					}
 				}
-				updateUI := false
-				if GetBlockHeight(cd, cs) && !updateUI {
-					mui.as.scs[cd.ID] = *cs
-					updateUI = true
+				// The conf may have changed since this poller was started
+				data, ok := as.chainData(id)
+				if !ok {
+					data = *cd
 				}
-				if updateUI {
-					mui.Refresh()
+				if data.Driver().HealthCheck(&data, &cur) {
+					as.setPolledState(id, cur)
+					as.Refresh()
 				}
-			case <-cs.ChainStateUpdate.quit:
-				cs.ChainStateUpdate.timer.Stop()
+			case <-csu.quit:
+				csu.timer.Stop()
 				return
 			}
 		}
 	}()
 }
 
-func DrivechainMine(as *AppState, mui *MainUI) {
-	_, err := MakeRpcRequest(&as.dcd, "generate", []interface{}{1})
-	if err != nil {
-		println(err.Error())
+// DrivechainMine generates blocks on the drivechain, only usable on regtest.
+func DrivechainMine(as *AppState, blocks int) error {
+	dcd := as.drivechainData()
+	if !canGenerate(&dcd) {
+		return errNotRegtest
 	}
+	c := dcd.RPCClient()
+	if blocks > 100 {
+		c.Timeout = time.Minute
+	}
+	_, err := c.Do(context.Background(), "generate", blocks)
+	return err
+}
+
+type mempoolInfo struct {
+	Size int `json:"size"`
 }
 
-func GetBlockHeight(cd *ChainData, cs *ChainState) bool {
-	currentHeight := cs.Height
-	currnetState := cs.State
-	bcr, err := MakeRpcRequest(cd, "getblockcount", []interface{}{})
+// PollChainState fetches height, balances, mempool size and best block hash
+// in a single batch request. Returns true if anything changed.
+func PollChainState(cd *ChainData, cs *ChainState) bool {
+	prev := *cs
+	var (
+		height      int
+		balance     float64
+		unconfirmed float64
+		mempool     mempoolInfo
+		bestHash    string
+	)
+	heightCall := rpc.NewBatchCall(&height, "getblockcount")
+	balanceCall := rpc.NewBatchCall(&balance, "getbalance")
+	unconfirmedCall := rpc.NewBatchCall(&unconfirmed, "getunconfirmedbalance")
+	mempoolCall := rpc.NewBatchCall(&mempool, "getmempoolinfo")
+	bestHashCall := rpc.NewBatchCall(&bestHash, "getbestblockhash")
+
+	ctx, cancel := rpcContext()
+	defer cancel()
+	err := cd.RPCClient().Batch(ctx, []*rpc.BatchCall{heightCall, balanceCall, unconfirmedCall, mempoolCall, bestHashCall})
+	if err == nil {
+		err = heightCall.Err
+	}
 	if err != nil {
 		println(err.Error())
 		cs.State = Unknown
-		if currnetState != cs.State {
-			return true
-		}
-	} else {
-		defer bcr.Body.Close()
-		if bcr.StatusCode == 200 {
-			var res RPCGetBlockCountResponse
-			err := json.NewDecoder(bcr.Body).Decode(&res)
-			if err == nil {
-				cs.Height = res.Result
-				cs.State = Running
-				changed := false
-				if currentHeight != cs.Height {
-					changed = true
-				}
-				if currnetState != cs.State {
-					changed = true
-				}
-				return changed
-			}
-		}
+		return prev.State != cs.State
 	}
-	return false
-}
 
-func GetBalance(cd *ChainData, cs *ChainState) bool {
-	currentBalance := cs.AvailableBalance
-	bcr, err := MakeRpcRequest(cd, "getbalance", []interface{}{})
-	if err != nil {
-		println(err.Error())
-	} else {
-		defer bcr.Body.Close()
-		if bcr.StatusCode == 200 {
-			var res RPCGetBalanceResponse
-			err := json.NewDecoder(bcr.Body).Decode(&res)
-			if err == nil {
-				cs.AvailableBalance = res.Result
-				if currentBalance != cs.AvailableBalance {
-					return true
-				}
-			}
-		}
+	cs.State = Running
+	cs.Height = height
+	// Wallet calls fail while no wallet is loaded, keep the last known values
+	if balanceCall.Err == nil {
+		cs.AvailableBalance = balance
+	}
+	if unconfirmedCall.Err == nil {
+		cs.PendingBalance = unconfirmed
+	}
+	if mempoolCall.Err == nil {
+		cs.MempoolSize = mempool.Size
 	}
-	return false
+	if bestHashCall.Err == nil {
+		cs.BestBlockHash = bestHash
+	}
+
+	return prev.State != cs.State ||
+		prev.Height != cs.Height ||
+		prev.AvailableBalance != cs.AvailableBalance ||
+		prev.PendingBalance != cs.PendingBalance ||
+		prev.MempoolSize != cs.MempoolSize ||
+		prev.BestBlockHash != cs.BestBlockHash
 }
 
-func NeedsActivation(cd *ChainData, as *AppState) bool {
-	ls, err := MakeRpcRequest(&as.dcd, "listactivesidechains", []interface{}{})
+// NeedsActivation reports whether the sidechain still has to be proposed and
+// activated on the drivechain.
+func NeedsActivation(cd *ChainData, as *AppState) (bool, error) {
+	ctx, cancel := rpcContext()
+	defer cancel()
+	dcd := as.drivechainData()
+	active, err := rpc.Call[[]ActiveSidechain](ctx, dcd.RPCClient(), "listactivesidechains")
 	if err != nil {
-		println(err.Error())
-	} else if ls.StatusCode == 200 {
-		defer ls.Body.Close()
-		var res RPCCreateSidechainProposalResponse
-		err := json.NewDecoder(ls.Body).Decode(&res)
-		if err == nil {
-			for _, sc := range res.Result {
-				if sc.Title == cd.ID {
-					return false
-				}
-			}
-			return true
+		return true, err
+	}
+	for _, sc := range active {
+		if sc.Title == cd.ID {
+			return false, nil
 		}
 	}
-	return true
+	return true, nil
 }
 
-func CreateSidechainProposal(as *AppState, cd *ChainData, cs *ChainState) bool {
+func CreateSidechainProposal(as *AppState, cd *ChainData, cs *ChainState) error {
 	println("Creating sidechain proposal...")
-	pr, err := MakeRpcRequest(&as.dcd, "createsidechainproposal", []interface{}{cd.Slot, cd.ID})
-	if err != nil {
-		println(err.Error())
-	} else if pr.StatusCode == 200 {
-		_, err := MakeRpcRequest(&as.dcd, "generate", []interface{}{201})
-		if err != nil {
-			println(err.Error())
-		}
+	ctx := context.Background()
+	dcd := as.drivechainData()
+	c := dcd.RPCClient()
+	// Generating the activation blocks can take a while on slow machines
+	c.Timeout = time.Minute
+	if _, err := c.Do(ctx, "createsidechainproposal", cd.Slot, cd.ID); err != nil {
+		return err
+	}
+	if !canGenerate(&dcd) {
+		println("The proposal for " + cd.ID + " activates once miners on " + dcd.Network + " acked it")
+		return nil
 	}
-	return true
+	// Mine enough blocks for the proposal to activate
+	_, err := c.Do(ctx, "generate", 201)
+	return err
 }
 
-func LatestCoreCreateWallet(as *AppState, cd *ChainData, cs *ChainState) bool {
-	if cd.ID != "latestcore" {
-		return false
+func LatestCoreCreateWallet(as *AppState, cd *ChainData, cs *ChainState) error {
+	if cd.Kind != KindLatestCore {
+		return fmt.Errorf("%s does not use a created wallet", cd.ID)
 	}
 
 	println("Creating latest core wallet...")
-	pr, err := MakeRpcRequest(cd, "createwallet", []interface{}{"wallet", false, false, "", true, false, true, false})
-	if err != nil {
-		println(err.Error())
-		return false
-	} else if pr.StatusCode == 200 {
-		return true
-	}
-	return true
+	ctx, cancel := rpcContext()
+	defer cancel()
+	_, err := cd.RPCClient().Do(ctx, "createwallet", "wallet", false, false, "", true, false, true, false)
+	return err
 }
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"dc-launcher/conf"
+)
+
+// ChainSettings are the settings of a chain's conf file shown in its
+// settings dialog.
+type ChainSettings struct {
+	RPCPort     int      `json:"rpcport"`
+	RPCUser     string   `json:"rpcuser"`
+	RPCPassword string   `json:"rpcpassword"`
+	DataDir     string   `json:"datadir"`
+	Network     string   `json:"network"` // Read only
+	Debug       []string `json:"debug,omitempty"`
+	// Only apply to sidechains
+	Slot       int  `json:"slot,omitempty"`
+	RefreshBMM bool `json:"refreshbmm"`
+	BMMFee     bool `json:"bmmfee"`
+}
+
+func chainConf(cd *ChainData) (*conf.Config, error) {
+	return conf.Load(filepath.Join(cd.ConfDir, cd.ConfName), cd.ConfDir)
+}
+
+// LoadChainSettings reads the settings of cd from its conf file, settings
+// missing from it are what the launcher uses.
+func LoadChainSettings(cd *ChainData) (ChainSettings, error) {
+	s := ChainSettings{
+		RPCPort:     cd.Port,
+		RPCUser:     cd.RPCUser,
+		RPCPassword: cd.RPCPass,
+		DataDir:     cd.ConfDir,
+		Slot:        cd.Slot,
+	}
+	c, err := chainConf(cd)
+	if err != nil {
+		return s, err
+	}
+	s.Network = c.Network()
+	if v, ok, err := c.GetInt("rpcport"); err != nil {
+		return s, err
+	} else if ok {
+		s.RPCPort = v
+	}
+	if v, ok := c.Get("rpcuser"); ok {
+		s.RPCUser = v
+	}
+	if v, ok := c.Get("rpcpassword"); ok {
+		s.RPCPassword = v
+	}
+	if v, ok := c.Get("datadir"); ok {
+		s.DataDir = v
+	}
+	s.Debug = c.GetAll("debug")
+	if v, ok, err := c.GetInt("slot"); err != nil {
+		return s, err
+	} else if ok {
+		s.Slot = v
+	}
+	s.RefreshBMM, _ = c.GetBool("refreshbmm")
+	s.BMMFee, _ = c.GetBool("bmmfee")
+	return s, nil
+}
+
+var debugCategory = regexp.MustCompile(`^[a-z0-9]+$`)
+
+func validatePort(v string) error {
+	port, err := strconv.Atoi(v)
+	if err != nil || port < 1 || port > 65535 {
+		return errors.New("must be a port between 1 and 65535")
+	}
+	return nil
+}
+
+func validateSlot(v string) error {
+	slot, err := strconv.Atoi(v)
+	if err != nil || slot < 0 || slot > 255 {
+		return errors.New("must be a slot between 0 and 255")
+	}
+	return nil
+}
+
+// Both are empty for cookie authentication.
+func validateRPCUser(v string) error {
+	if strings.ContainsAny(v, ":# \t") {
+		return errors.New("can't contain :, # or spaces")
+	}
+	return nil
+}
+
+func validateRPCPassword(v string) error {
+	if strings.ContainsAny(v, "# \t") {
+		return errors.New("can't contain # or spaces")
+	}
+	return nil
+}
+
+func validateCredentials(user string, pass string) error {
+	if (user == "") != (pass == "") {
+		return errors.New("and rpcuser must both be set or both be empty for cookie authentication")
+	}
+	return nil
+}
+
+func validateDataDir(v string) error {
+	if !filepath.IsAbs(v) {
+		return errors.New("must be an absolute path")
+	}
+	if strings.Contains(v, "#") {
+		return errors.New("can't contain #")
+	}
+	if fi, err := os.Stat(v); err == nil && !fi.IsDir() {
+		return errors.New("is not a directory")
+	}
+	return nil
+}
+
+func validateDebug(categories []string) error {
+	for _, c := range categories {
+		if !debugCategory.MatchString(c) {
+			return fmt.Errorf("%q is not a debug category", c)
+		}
+	}
+	return nil
+}
+
+// Validate checks s before it is written to the conf file of cd.
+func (s ChainSettings) Validate(cd *ChainData) error {
+	type check struct {
+		name string
+		err  error
+	}
+	checks := []check{{"rpcport", validatePort(strconv.Itoa(s.RPCPort))}}
+	// The rest is read by bitcoind style chains only
+	if cd.Driver().SupportsRPC() {
+		checks = append(checks,
+			check{"rpcuser", validateRPCUser(s.RPCUser)},
+			check{"rpcpassword", validateRPCPassword(s.RPCPassword)},
+			check{"rpcpassword", validateCredentials(s.RPCUser, s.RPCPassword)},
+			check{"datadir", validateDataDir(s.DataDir)},
+			check{"debug", validateDebug(s.Debug)},
+		)
+	}
+	if !cd.IsDrivechain {
+		checks = append(checks, check{"slot", validateSlot(strconv.Itoa(s.Slot))})
+	}
+	for _, c := range checks {
+		if c.err != nil {
+			return fmt.Errorf("%s %s", c.name, c.err)
+		}
+	}
+	return nil
+}
+
+// confSection returns the section of the main conf file key is set in or
+// should be added to. Settings are changed where they are, e.g. in [regtest]
+// for latestcore, and new ones go to the network's section if the file has
+// one. Ports and other network only keys always go to the network's section
+// on test networks, bitcoind ignores them anywhere else.
+func confSection(c *conf.Config, key string) string {
+	f, net := c.Main(), c.Network()
+	if net != "main" && conf.NetworkOnly(key) {
+		return net
+	}
+	if _, ok := f.Get(net, key); ok {
+		return net
+	}
+	if _, ok := f.Get("", key); !ok && net != "main" && len(f.Keys(net)) > 0 {
+		return net
+	}
+	return ""
+}
+
+// setConfValue sets a single key in the conf file of cd.
+func setConfValue(cd *ChainData, key string, value string) error {
+	c, err := chainConf(cd)
+	if err != nil {
+		return err
+	}
+	f := c.Main()
+	if err := f.Set(confSection(c, key), key, value); err != nil {
+		return err
+	}
+	return f.WriteFile(c.Path, 0o600)
+}
+
+// SaveChainSettings writes the settings that changed to the conf file of
+// cd, keeping everything else in it as it is. A running chain picks them up
+// when it is restarted.
+func SaveChainSettings(cd *ChainData, s ChainSettings) error {
+	if err := s.Validate(cd); err != nil {
+		return err
+	}
+	old, err := LoadChainSettings(cd)
+	if err != nil {
+		return err
+	}
+	c, err := chainConf(cd)
+	if err != nil {
+		return err
+	}
+	f := c.Main()
+	section := func(key string) string {
+		return confSection(c, key)
+	}
+	set := func(key string, value string, changed bool) error {
+		if !changed {
+			return nil
+		}
+		if value == "" {
+			f.Unset(section(key), key)
+			return nil
+		}
+		return f.Set(section(key), key, value)
+	}
+	setBool := func(key string, value bool, changed bool) error {
+		if !changed {
+			return nil
+		}
+		f.Unset(section("no"+key), "no"+key)
+		if value {
+			return f.Set(section(key), key, "1")
+		}
+		return f.Set(section(key), key, "0")
+	}
+
+	errs := []error{
+		set("rpcport", strconv.Itoa(s.RPCPort), s.RPCPort != old.RPCPort),
+		set("rpcuser", s.RPCUser, s.RPCUser != old.RPCUser),
+		set("rpcpassword", s.RPCPassword, s.RPCPassword != old.RPCPassword),
+		set("datadir", s.DataDir, s.DataDir != old.DataDir),
+	}
+	if strings.Join(s.Debug, ",") != strings.Join(old.Debug, ",") {
+		sec := section("debug")
+		f.Unset(sec, "debug")
+		for _, d := range s.Debug {
+			errs = append(errs, f.Add(sec, "debug", d))
+		}
+	}
+	if !cd.IsDrivechain {
+		errs = append(errs,
+			set("slot", strconv.Itoa(s.Slot), s.Slot != old.Slot),
+			setBool("refreshbmm", s.RefreshBMM, s.RefreshBMM != old.RefreshBMM),
+			setBool("bmmfee", s.BMMFee, s.BMMFee != old.BMMFee),
+		)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	return f.WriteFile(c.Path, 0o600)
+}
@@ -0,0 +1,850 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const cliUsage = `Usage: dc-launcher [options] <command> [arguments]
+
+Runs the launcher without a window. Without a command the UI is started.
+
+Options:
+  --profile <name>         use a named profile instead of the default one
+  --node <n>               use node n of the profile, 1 by default
+  --rpcport <chain>=<port> use this RPC port, before the chain's conf file
+  --slot <chain>=<slot>    use this sidechain slot, before the chain's conf file
+
+Commands:
+  start <chain>...        launch chains, sidechains are activated if needed
+  stop <chain>... | --all stop chains, stopping drivechain stops all sidechains
+  status [--json]         show the state of every chain
+  mine <blocks>           generate blocks on the drivechain
+  reset [--yes]           stop everything and delete all chain data
+  reset [--scope chain|wallet|all] [--backup] [--dry-run] [--yes] [<chain>...]
+                          reset single chains, scope all by default
+  backup [--encrypt] <chain>...
+                          back up the wallets of chains to ~/.dclauncher/backups
+  backups [<chain>...]    list the wallet backups
+  restore [--yes] <chain> <backup>
+                          replace the wallet of a chain with a backup
+  versions <chain>        list installed versions and check for updates
+  update <chain>          install the latest version and switch to it
+  use <chain> <version>   switch to an installed version, e.g. to roll back
+  verify [<chain>...]     check installed binaries haven't been modified
+  network [<network>]     show or switch the network: regtest, signet or testnet
+  ports [--fix] [<chain>...]
+                          check for port conflicts, --fix moves chains to free ports
+  profiles [create <name>]
+                          list the profiles or create one
+  nodes [<n>]             list the nodes of the profile or create nodes up to n
+  daemon                  run the launcher daemon in the foreground
+
+Commands are sent to the launcher daemon when one is running, otherwise they
+run in this process. Each profile and node has its own daemon. --rpcport and --slot
+only apply in this process, pass them to dc-launcher daemon to use them with
+the daemon.
+`
+
+// chainValues collects repeated <chain>=<number> flags.
+type chainValues map[string]int
+
+func (v chainValues) String() string {
+	return fmt.Sprint(map[string]int(v))
+}
+
+func (v chainValues) Set(s string) error {
+	id, n, ok := strings.Cut(s, "=")
+	if !ok || id == "" {
+		return fmt.Errorf("%q is not <chain>=<number>", s)
+	}
+	i, err := strconv.Atoi(n)
+	if err != nil {
+		return fmt.Errorf("%q is not <chain>=<number>", s)
+	}
+	v[id] = i
+	return nil
+}
+
+// cliOptions are the options given before the command.
+type cliOptions struct {
+	profile string // Empty if not given
+	node    int
+	ports   chainValues
+	slots   chainValues
+}
+
+func (o cliOptions) set() bool {
+	return len(o.ports) > 0 || len(o.slots) > 0
+}
+
+// newAppState returns the state for a launcher running in this process.
+func (o cliOptions) newAppState() *AppState {
+	as := NewAppState()
+	as.detach = true
+	as.flagPorts = o.ports
+	as.flagSlots = o.slots
+	return as
+}
+
+// parseCLIOptions parses the options before the command and selects the
+// profile. It returns the exit code if the launcher should exit.
+func parseCLIOptions(args []string) (cliOptions, []string, int, bool) {
+	o := cliOptions{ports: chainValues{}, slots: chainValues{}}
+	fs := flag.NewFlagSet("dc-launcher", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&o.profile, "profile", "", "")
+	fs.IntVar(&o.node, "node", 1, "")
+	fs.Var(o.ports, "rpcport", "")
+	fs.Var(o.slots, "slot", "")
+	if err := fs.Parse(args); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "%s\n\n", err)
+		}
+		fmt.Fprint(os.Stderr, cliUsage)
+		return o, nil, 2, true
+	}
+	if err := SetProfile(o.profile); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return o, nil, 1, true
+	}
+	if err := SetNode(o.node); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return o, nil, 1, true
+	}
+	return o, fs.Args(), 0, false
+}
+
+// RunCLI runs a headless launcher command and returns the exit code.
+func RunCLI(o cliOptions, args []string) int {
+	if len(args) == 0 || args[0] == "help" {
+		fmt.Fprint(os.Stderr, cliUsage)
+		return 2
+	}
+
+	cmd, args := args[0], args[1:]
+	var err error
+	switch cmd {
+	case "daemon":
+		err = runDaemonCommand(o)
+	case "profiles":
+		err = cliProfiles(args)
+	case "nodes":
+		err = cliNodes(args)
+	default:
+		return runControllerCommand(o, cmd, args)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	return 0
+}
+
+// runControllerCommand runs a command on the launcher daemon or in this
+// process.
+func runControllerCommand(o cliOptions, cmd string, args []string) int {
+
+	commands := map[string]func(Controller, []string) error{
+		"start":    cliStart,
+		"stop":     cliStop,
+		"status":   cliStatus,
+		"mine":     cliMine,
+		"reset":    cliReset,
+		"backup":   cliBackup,
+		"backups":  cliBackups,
+		"restore":  cliRestore,
+		"versions": cliVersions,
+		"update":   cliUpdate,
+		"use":      cliUse,
+		"verify":   cliVerify,
+		"ports":    cliPorts,
+		"network":  cliNetwork,
+	}
+	run, ok := commands[cmd]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, cliUsage)
+		return 2
+	}
+
+	ctl, err := cliController(o)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	if err := run(ctl, args); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	return 0
+}
+
+func runDaemonCommand(o cliOptions) error {
+	as := o.newAppState()
+	if err := ConfInit(as); err != nil {
+		return err
+	}
+	return RunDaemon(as)
+}
+
+// cliController talks to the launcher daemon if one is running, otherwise
+// the command runs in this process.
+func cliController(o cliOptions) (Controller, error) {
+	sock, err := controlSocketPath()
+	if err != nil {
+		return nil, err
+	}
+	if ControlSocketAlive(sock) {
+		if o.set() {
+			return nil, errors.New("the launcher daemon is running, --rpcport and --slot only apply when passed to dc-launcher daemon")
+		}
+		return NewControlClient(sock), nil
+	}
+
+	as := o.newAppState()
+	if err := ConfInit(as); err != nil {
+		return nil, err
+	}
+	AdoptRunningChains(as, false)
+	return NewLocalController(as, true), nil
+}
+
+// chainStatus returns the status of chain id.
+func chainStatus(ctl Controller, id string) (ChainStatus, error) {
+	statuses, err := ctl.Status()
+	if err != nil {
+		return ChainStatus{}, err
+	}
+	for _, st := range statuses {
+		if st.ID == id {
+			return st, nil
+		}
+	}
+	return ChainStatus{}, fmt.Errorf("unknown chain %q", id)
+}
+
+// waitForRunning polls the chain until it answers over RPC or timeout passed.
+func waitForRunning(ctl Controller, id string, timeout time.Duration) (ChainStatus, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		st, err := chainStatus(ctl, id)
+		if err != nil {
+			return st, err
+		}
+		if st.State == Running {
+			return st, nil
+		}
+		if time.Now().After(deadline) {
+			return st, fmt.Errorf("start %s: no RPC response after %v", id, timeout)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func cliStart(ctl Controller, args []string) error {
+	fs := flag.NewFlagSet("start", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 2*time.Minute, "how long to wait for each chain to answer over RPC")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("start: no chain given")
+	}
+
+	providers, err := ctl.Providers()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range fs.Args() {
+		fmt.Printf("Launching %s...\n", id)
+		if err := ctl.Start(id); err != nil {
+			var le *LaunchError
+			if errors.As(err, &le) && le.Fix != "" {
+				return fmt.Errorf("%w\n%s", err, le.Fix)
+			}
+			return err
+		}
+
+		// Nothing to wait for without RPC
+		if _, drv, err := DriverFor(providers[id]); err != nil || !drv.SupportsRPC() {
+			continue
+		}
+		st, err := waitForRunning(ctl, id, *timeout)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s running at height %d\n", id, st.Height)
+	}
+	return nil
+}
+
+func cliStop(ctl Controller, args []string) error {
+	fs := flag.NewFlagSet("stop", flag.ContinueOnError)
+	all := fs.Bool("all", false, "stop drivechain and every sidechain")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ids := fs.Args()
+	if *all {
+		providers, err := ctl.Providers()
+		if err != nil {
+			return err
+		}
+		// Stopping drivechain stops all sidechains first
+		ids = []string{drivechainID(providers)}
+	}
+	if len(ids) == 0 {
+		return errors.New("stop: no chain given, use --all to stop everything")
+	}
+
+	// StopChain logs every step itself when running in process
+	var progress StopProgress
+	if _, remote := ctl.(*ControlClient); remote {
+		progress = func(step string) {
+			fmt.Println(step)
+		}
+	}
+	for _, id := range ids {
+		if err := ctl.Stop(id, progress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cliStatus(ctl Controller, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print status as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	statuses, err := ctl.Status()
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(statuses)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHAIN\tSTATE\tPID\tHEIGHT\tBALANCE\tMEMPOOL\tRPC PORT")
+	for _, st := range statuses {
+		pid := "-"
+		if st.PID != 0 {
+			pid = strconv.Itoa(st.PID)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%.8f\t%d\t%d\n", st.ID, st.State, pid, st.Height, st.AvailableBalance, st.MempoolSize, st.RPCPort)
+	}
+	return tw.Flush()
+}
+
+func cliNetwork(ctl Controller, args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage: dc-launcher network [regtest|signet|testnet]")
+	}
+	if len(args) == 1 {
+		n, err := ParseNetwork(args[0])
+		if err != nil {
+			return err
+		}
+		if err := ctl.SetNetwork(n); err != nil {
+			return err
+		}
+		statuses, err := ctl.Status()
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			if st.State != Unknown && st.Network != "" && st.Network != n.Section() {
+				fmt.Printf("%s still runs on %s, restart it to switch\n", st.ID, st.Network)
+			}
+		}
+	}
+	n, err := ctl.Network()
+	if err != nil {
+		return err
+	}
+	fmt.Println(n)
+	return nil
+}
+
+func cliPorts(ctl Controller, args []string) error {
+	fs := flag.NewFlagSet("ports", flag.ContinueOnError)
+	fix := fs.Bool("fix", false, "move chains to free ports")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *fix {
+		changes, err := ctl.FixPorts(fs.Args())
+		for _, c := range changes {
+			fmt.Printf("%s: %s port %d -> %d\n", c.ChainID, c.Use, c.From, c.To)
+		}
+		if err == nil && len(changes) == 0 {
+			fmt.Println("No port conflicts")
+		}
+		return err
+	}
+
+	ports, err := ctl.Ports()
+	if err != nil {
+		return err
+	}
+	only := make(map[string]bool)
+	for _, id := range fs.Args() {
+		only[id] = true
+	}
+	var conflicts int
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHAIN\tUSE\tPORT\tFROM\tSTATE")
+	for _, p := range ports {
+		if len(only) > 0 && !only[p.ChainID] {
+			continue
+		}
+		if p.Conflict() {
+			conflicts++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", p.ChainID, p.Use, p.Port, p.Source, p)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if conflicts > 0 {
+		return fmt.Errorf("ports: %d conflicts, dc-launcher ports --fix moves chains to free ports", conflicts)
+	}
+	return nil
+}
+
+func cliMine(ctl Controller, args []string) error {
+	blocks := 1
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("mine: invalid block count %q", args[0])
+		}
+		blocks = n
+	}
+	if err := ctl.Mine(blocks); err != nil {
+		return err
+	}
+	providers, err := ctl.Providers()
+	if err != nil {
+		return err
+	}
+	st, err := chainStatus(ctl, drivechainID(providers))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Mined %d blocks, drivechain height %d\n", blocks, st.Height)
+	return nil
+}
+
+func cliReset(ctl Controller, args []string) error {
+	fs := flag.NewFlagSet("reset", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	scope := fs.String("scope", "", "what to delete: chain, wallet or all")
+	backup := fs.Bool("backup", false, "archive the files before deleting them")
+	dryRun := fs.Bool("dry-run", false, "only list what would be deleted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	ids := fs.Args()
+	if len(ids) == 0 && *scope == "" && !*backup && !*dryRun {
+		if !*yes && !confirm(in, "This will delete all data and settings for Drivechain and Sidechains. Continue?") {
+			return errors.New("reset aborted")
+		}
+		return ctl.Reset()
+	}
+
+	if *scope == "" {
+		*scope = string(ResetAll)
+	}
+	s, err := ParseResetScope(*scope)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		statuses, err := ctl.Status()
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			ids = append(ids, st.ID)
+		}
+	}
+
+	// List what goes first, also to confirm it
+	opts := ResetOptions{Scope: s, Backup: *backup, DryRun: true}
+	n := 0
+	for _, id := range ids {
+		report, err := ctl.ResetChain(id, opts)
+		if err != nil {
+			return err
+		}
+		for _, p := range report.Paths {
+			fmt.Printf("%s: %s\n", id, p)
+		}
+		n += len(report.Paths)
+	}
+	if n == 0 {
+		fmt.Println("Nothing to delete")
+		return nil
+	}
+	if *dryRun {
+		return nil
+	}
+	if !*yes && !confirm(in, "Stop the chains and delete these files?") {
+		return errors.New("reset aborted")
+	}
+
+	opts.DryRun = false
+	for _, id := range ids {
+		report, err := ctl.ResetChain(id, opts)
+		if report.Backup != "" {
+			fmt.Printf("%s: backed up to %s\n", id, report.Backup)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// confirm asks a yes or no question on the terminal, no by default. Every
+// prompt of a command reads from the same in, a reader of its own would
+// buffer the answers to the prompts after it when stdin is a pipe.
+func confirm(in *bufio.Reader, question string) bool {
+	fmt.Print(question + " [y/N] ")
+	answer, _ := in.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// backupPassphraseEnv holds the passphrase of encrypted wallet backups, for
+// scripts. Without it the passphrase is asked for on the terminal.
+const backupPassphraseEnv = "DCLAUNCHER_BACKUP_PASSPHRASE"
+
+// readPassphrase asks for a passphrase without echoing it.
+func readPassphrase(in *bufio.Reader, prompt string) (string, error) {
+	if p := os.Getenv(backupPassphraseEnv); p != "" {
+		return p, nil
+	}
+	fmt.Print(prompt)
+	stty := exec.Command("stty", "-echo")
+	stty.Stdin = os.Stdin
+	if stty.Run() == nil {
+		defer func() {
+			echo := exec.Command("stty", "echo")
+			echo.Stdin = os.Stdin
+			echo.Run()
+			fmt.Println()
+		}()
+	}
+	p, err := in.ReadString('\n')
+	if err != nil && p == "" {
+		return "", err
+	}
+	p = strings.TrimRight(p, "\r\n")
+	if p == "" {
+		return "", errors.New("empty passphrase")
+	}
+	return p, nil
+}
+
+func cliBackup(ctl Controller, args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	encrypt := fs.Bool("encrypt", false, "encrypt the backup with a passphrase")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("backup: no chain given")
+	}
+	var opts BackupOptions
+	if *encrypt {
+		in := bufio.NewReader(os.Stdin)
+		p, err := readPassphrase(in, "Passphrase: ")
+		if err != nil {
+			return err
+		}
+		if os.Getenv(backupPassphraseEnv) == "" {
+			again, err := readPassphrase(in, "Repeat the passphrase: ")
+			if err != nil {
+				return err
+			}
+			if again != p {
+				return errors.New("the passphrases differ")
+			}
+		}
+		opts.Passphrase = p
+	}
+	for _, id := range fs.Args() {
+		b, err := ctl.BackupWallet(id, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+		fmt.Printf("%s: backed up to %s\n", id, b.Path)
+	}
+	return nil
+}
+
+func cliBackups(ctl Controller, args []string) error {
+	ids := args
+	if len(ids) == 0 {
+		statuses, err := ctl.Status()
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			ids = append(ids, st.ID)
+		}
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHAIN\tTIME\tSIZE\tENCRYPTED\tPATH")
+	for _, id := range ids {
+		backups, err := ctl.WalletBackups(id)
+		if err != nil {
+			return err
+		}
+		for _, b := range backups {
+			encrypted := "no"
+			if b.Encrypted {
+				encrypted = "yes"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", id, b.Time.Format("2006-01-02 15:04:05"), b.Size, encrypted, b.Path)
+		}
+	}
+	return tw.Flush()
+}
+
+func cliRestore(ctl Controller, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return errors.New("restore: expected a chain and a backup")
+	}
+	id, file := fs.Arg(0), fs.Arg(1)
+	// A bare name from dc-launcher backups is in the backups directory
+	if _, err := os.Stat(file); os.IsNotExist(err) && filepath.Base(file) == file {
+		if dir, err := backupsDir(); err == nil {
+			file = filepath.Join(dir, file)
+		}
+	}
+	// The daemon runs in another directory
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	in := bufio.NewReader(os.Stdin)
+	opts := RestoreOptions{File: file}
+	encrypted, err := isEncryptedBackup(file)
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		if opts.Passphrase, err = readPassphrase(in, "Passphrase: "); err != nil {
+			return err
+		}
+	}
+	if !*yes && !confirm(in, fmt.Sprintf("Replace the wallet of %s with %s? A running chain is stopped and started again", id, file)) {
+		return errors.New("restore aborted")
+	}
+	replaced, err := ctl.RestoreWallet(id, opts)
+	if replaced.Path != "" {
+		fmt.Printf("%s: the previous wallet was backed up to %s\n", id, replaced.Path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", id, err)
+	}
+	fmt.Printf("%s: restored %s\n", id, file)
+	return nil
+}
+
+func cliVersions(ctl Controller, args []string) error {
+	if len(args) != 1 {
+		return errors.New("versions: expected one chain")
+	}
+	st, err := chainStatus(ctl, args[0])
+	if err != nil {
+		return err
+	}
+	vi := st.Versions
+	if len(vi.Installed) == 0 {
+		fmt.Println("No versions installed")
+	}
+	for _, v := range vi.Installed {
+		mark := " "
+		if v == vi.Active {
+			mark = "*"
+		}
+		fmt.Printf("%s %s\n", mark, v)
+	}
+	if vi.UpdateAvailable {
+		fmt.Printf("Update available: %s\n", vi.Latest)
+	}
+	return nil
+}
+
+func cliUpdate(ctl Controller, args []string) error {
+	if len(args) != 1 {
+		return errors.New("update: expected one chain")
+	}
+	if err := ctl.Update(args[0]); err != nil {
+		return err
+	}
+	st, err := chainStatus(ctl, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s now uses version %s\n", args[0], st.Versions.Active)
+	return nil
+}
+
+func cliUse(ctl Controller, args []string) error {
+	if len(args) != 2 {
+		return errors.New("use: expected a chain and a version")
+	}
+	return ctl.UseVersion(args[0], args[1])
+}
+
+func cliVerify(ctl Controller, args []string) error {
+	ids := args
+	if len(ids) == 0 {
+		statuses, err := ctl.Status()
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			ids = append(ids, st.ID)
+		}
+	}
+
+	var failed []string
+	for _, id := range ids {
+		r, err := ctl.Verify(id)
+		if err != nil {
+			return err
+		}
+		name := id
+		if r.Version != "" {
+			name += " " + r.Version
+		}
+		switch {
+		case !r.OK():
+			failed = append(failed, id)
+			fmt.Printf("%s: MODIFIED\n", name)
+			for _, p := range r.Problems {
+				fmt.Printf("  %s\n", p)
+			}
+		case r.Note != "":
+			fmt.Printf("%s: %s\n", name, r.Note)
+		default:
+			signed := "not signed"
+			if r.Signed {
+				signed = "signed"
+			}
+			fmt.Printf("%s: ok, %d files, %s\n", name, r.Files, signed)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("verify: %s modified", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func cliProfiles(args []string) error {
+	if len(args) == 2 && args[0] == "create" {
+		s, err := CreateProfile(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("created %s, ports moved by %d\n", args[1], s.PortOffset)
+		return nil
+	}
+	if len(args) > 0 {
+		return errors.New("usage: dc-launcher profiles [create <name>]")
+	}
+
+	names, err := ListProfiles()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROFILE\tPORT OFFSET\tROOT")
+	for _, name := range names {
+		root, err := profileRoot(name)
+		if err != nil {
+			return err
+		}
+		s, err := readSettings(filepath.Join(root, ".dclauncher"))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		mark := ""
+		if name == profile {
+			mark = " *"
+		}
+		fmt.Fprintf(tw, "%s%s\t%d\t%s\n", name, mark, s.PortOffset, root)
+	}
+	return tw.Flush()
+}
+
+func cliNodes(args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage: dc-launcher nodes [<n>]")
+	}
+	if len(args) == 1 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid number of nodes %q", args[0])
+		}
+		if _, err := CreateNodes(n); err != nil {
+			return err
+		}
+	}
+
+	nodes, err := ListNodes(profile)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tPORT OFFSET\tDAEMON\tROOT")
+	for _, n := range nodes {
+		root, err := nodeRoot(profile, n)
+		if err != nil {
+			return err
+		}
+		s, err := readSettings(filepath.Join(root, ".dclauncher"))
+		if err != nil {
+			return fmt.Errorf("node %d: %w", n, err)
+		}
+		daemon := "stopped"
+		if sock, err := nodeSocketPath(n); err == nil && ControlSocketAlive(sock) {
+			daemon = "running"
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\n", n, s.PortOffset, daemon, root)
+	}
+	return tw.Flush()
+}
@@ -1,43 +1,20 @@
 package main
 
 import (
-	"archive/zip"
-	"bufio"
-	"bytes"
 	_ "embed"
-	"encoding/json"
-	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
-	"runtime"
-	"strconv"
-	"strings"
+	"time"
+
+	"dc-launcher/conf"
 )
 
 const (
 	defaultChainProvidersConfName = "chains.json"
 )
 
-//go:embed binaries/linux/drivechain-qt-linux
-var drivechainLinux []byte
-
-//go:embed binaries/linux/testchain-qt-linux
-var testchainLinux []byte
-
-//go:embed binaries/linux/bitassets-qt-linux
-var bitassetsLinux []byte
-
-//go:embed binaries/linux/thunder-linux
-var thunderLinux []byte
-
-//go:embed binaries/linux/bitcoin-qt-linux
-var latestCoreLinux []byte
-
-//go:embed binaries/linux/bitnames.zip
-var bitnamesZipLinux []byte
-
 //go:embed chain.conf
 var chainConfBytes []byte
 
@@ -47,37 +24,70 @@ var chainsBytes []byte
 func ResetEverything(as *AppState) error {
 	// Stop all chains
 	// Stoping Drivechain will also stop sidechains
-	err := StopChain(&as.dcd, &as.dcs, as)
+	dcd, dcs := as.drivechainData(), as.drivechainState()
+	err := StopChain(&dcd, &dcs, as, nil)
 	if err != nil {
 		println(err.Error())
 	}
+	// Nothing is deleted while a chain process is still around
+	for _, id := range chainIDs(as) {
+		cd, _, err := chainByID(as, id)
+		if err != nil {
+			continue
+		}
+		if err := waitExited(as, cd, exitTimeout(cd)); err != nil {
+			return err
+		}
+	}
 
-	homeDir, err := os.UserHomeDir()
+	// Only the profile's data is deleted
+	homeDir, err := profileHome()
 	if err != nil {
 		println(err.Error())
 	}
 
-	err = os.RemoveAll(homeDir + string(os.PathSeparator) + ".dclauncher")
+	// Keep the launcher settings and the daemon socket and log, the daemon
+	// itself may be the one resetting, the other nodes and the backups,
+	// which may be all that is left of a wallet after the reset. The default
+	// profile's launcher directory also holds the other profiles and the
+	// shared cache.
+	dclauncherDir := homeDir + string(os.PathSeparator) + ".dclauncher"
+	entries, err := os.ReadDir(dclauncherDir)
 	if err != nil {
 		println(err.Error())
 	}
+	for _, e := range entries {
+		switch e.Name() {
+		case launcherSettingsName, controlSocketName, daemonLogName, nodesDir, backupsDirName:
+			continue
+		case profilesDir, artifactCacheDir:
+			if profile == defaultProfile {
+				continue
+			}
+		}
+		err = os.RemoveAll(dclauncherDir + string(os.PathSeparator) + e.Name())
+		if err != nil {
+			println(err.Error())
+		}
+	}
 
 	err = os.RemoveAll(homeDir + string(os.PathSeparator) + ".drivechain")
 	if err != nil {
 		println(err.Error())
 	}
 
-	for _, chainData := range as.scd {
+	for _, chainData := range as.sidechains() {
 		err = os.RemoveAll(chainData.ConfDir)
 		if err != nil {
 			println(err.Error())
 		}
 	}
 
+	// ConfInit replaces the chain states, stop the pollers of the old ones
+	quits := as.pollerQuits()
 	go func() {
-		as.dcs.ChainStateUpdate.quit <- struct{}{}
-		for _, chainState := range as.scs {
-			chainState.ChainStateUpdate.quit <- struct{}{}
+		for _, quit := range quits {
+			quit <- struct{}{}
 		}
 	}()
 
@@ -85,7 +95,7 @@ func ResetEverything(as *AppState) error {
 }
 
 func ConfInit(as *AppState) error {
-	homeDir, err := os.UserHomeDir()
+	homeDir, err := profileHome()
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -94,38 +104,23 @@ func ConfInit(as *AppState) error {
 	defaultLauncherDir := homeDir + string(os.PathSeparator) + ".dclauncher"
 	if _, err := os.Stat(defaultLauncherDir); os.IsNotExist(err) {
 		println("Creating " + defaultLauncherDir)
-		err = os.Mkdir(defaultLauncherDir, 0o755)
-		if err != nil {
-			println(err.Error())
-			return err
-		}
-	}
-
-	// Look for chains.json write if not found
-	// TODO: This should be pulled from a remote source
-	defaultChainProvidersConf := defaultLauncherDir + string(os.PathSeparator) + defaultChainProvidersConfName
-	if _, err := os.Stat(defaultChainProvidersConf); os.IsNotExist(err) {
-		println("Creating " + defaultChainProvidersConf)
-		err = os.WriteFile(defaultChainProvidersConf, chainsBytes, 0o755)
+		err = os.MkdirAll(defaultLauncherDir, 0o755)
 		if err != nil {
 			println(err.Error())
 			return err
 		}
 	}
 
-	// Now read in the chains.json file
-	chains, err := os.ReadFile(defaultChainProvidersConf)
+	chainProviders, err := LoadCatalogue(defaultLauncherDir)
 	if err != nil {
 		println(err.Error())
 		return err
 	}
-
-	var chainProviders map[string]ChainProvider
-	if err := json.Unmarshal(chains, &chainProviders); err != nil {
+	as.cp = chainProviders
+	if err := as.loadLauncherSettings(); err != nil {
 		println(err.Error())
 		return err
 	}
-	as.cp = chainProviders
 
 	for k, chainProvider := range chainProviders {
 
@@ -139,158 +134,138 @@ func ConfInit(as *AppState) error {
 			}
 		}
 
-		conf := confDir + string(os.PathSeparator) + chainProvider.DefaultConfName
-		if _, err := os.Stat(conf); os.IsNotExist(err) {
-			var confBytes []byte
-			if k == "thunder" {
-				confBytes = append(confBytes, fmt.Sprintf("\nrpcport=%v", chainProvider.DefaultPort)...)
-				confBytes = append(confBytes, fmt.Sprintf("\nslot=%v", chainProvider.DefaultSlot)...)
-			} else if k == "latestcore" {
-				confBytes = append(confBytes, "chain=regtest"...)
-				confBytes = append(confBytes, "\nserver=1"...)
-				confBytes = append(confBytes, "\nsplash=0"...)
-				confBytes = append(confBytes, fmt.Sprintf("\nslot=%v", chainProvider.DefaultSlot)...)
-				confBytes = append(confBytes, "\ndatadir="+confDir...)
-				confBytes = append(confBytes, "\n[regtest]"...)
-				confBytes = append(confBytes, "\nrpcuser=user"...)
-				confBytes = append(confBytes, "\nrpcpassword=password"...)
-				confBytes = append(confBytes, fmt.Sprintf("\nrpcport=%v", chainProvider.DefaultPort)...)
-			} else {
-				confBytes = chainConfBytes
-				confBytes = append(confBytes, "\ndatadir="+confDir...)
-				confBytes = append(confBytes, fmt.Sprintf("\nrpcport=%v", chainProvider.DefaultPort)...)
-				if k != "drivechain" {
-					confBytes = append(confBytes, fmt.Sprintf("\nslot=%v", chainProvider.DefaultSlot)...)
-				}
-			}
-			err := os.WriteFile(conf, confBytes, 0o755)
-			println("Writing " + conf)
-			if err != nil {
-				println(err.Error())
-				return err
-			}
+		kind, drv, err := DriverFor(chainProvider)
+		if err != nil {
+			println(err.Error())
+			return err
 		}
 
-		// Read back in the confs
 		chainData := ChainData{}
 		chainData.ID = k
-		chainData.IsDrivechain = k == "drivechain"
+		chainData.Kind = kind
+		chainData.IsDrivechain = kind == KindDrivechain
 		chainData.BinName = chainProvider.BinName
 		chainData.ConfName = chainProvider.DefaultConfName
-		if k == "bitnames" {
-			chainData.BinDir = confDir + string(os.PathSeparator) + "usr" + string(os.PathSeparator) + "bin"
-		} else {
-			chainData.BinDir = confDir
-		}
+		chainData.Version = chainProvider.Version
 		chainData.ConfDir = confDir
+		chainData.InstallDir = confDir
+		chainData.BinDir = drv.BinDir(confDir)
+
+		confPath := confDir + string(os.PathSeparator) + chainProvider.DefaultConfName
+		if _, err := os.Stat(confPath); os.IsNotExist(err) {
+			if err := drv.WriteDefaultConf(chainProvider, &chainData); err != nil {
+				println(err.Error())
+				return err
+			}
+		}
 
-		err = loadConf(&chainData)
+		// Read back in the conf
+		err = as.loadChainConf(&chainData)
 		if err != nil {
 			println(err.Error())
 			return err
 		}
-
-		chainData.Port = chainProvider.DefaultPort
-		if k != "drivechain" {
-			chainData.Slot = chainProvider.DefaultSlot
+		if err := as.switchNetwork(&chainData); err != nil {
+			println(err.Error())
 		}
 
-		if k == "drivechain" {
-			as.dcd = chainData
-			as.dcs = ChainState{ID: k}
-		} else {
-			as.scd[k] = chainData
-			as.scs[k] = ChainState{ID: k, Slot: chainData.Slot}
+		chainData.StopTimeout = time.Duration(chainProvider.StopTimeout) * time.Second
+		chainData.Restart = chainProvider.Restart
+		chainData.Launch = chainProvider.Launch
+		chainData.Artifacts = chainProvider.Artifacts
+		chainData.SigningKey = chainProvider.SigningKey
+		if m, err := loadManifest(&chainData); err == nil {
+			applyManifest(&chainData, m)
 		}
 
-		// Write chain binary
-		if k == "drivechain" || k == "testchain" || k == "bitassets" || k == "thunder" || k == "latestcore" || k == "bitnames" {
-			err = writeBinary(&chainData)
-			if err != nil {
-				println(err.Error())
-				return err
-			}
+		cs := ChainState{ID: k}
+		if !chainData.IsDrivechain {
+			cs.Slot = chainData.Slot
 		}
-
+		as.setChainData(&chainData)
+		as.setChainState(k, cs)
 	}
+	secureCredentials(as)
 
 	return nil
 }
 
+// loadConf reads the settings the launcher needs from the chain's conf file
+// and the files it includes.
 func loadConf(chainData *ChainData) error {
-	readFile, err := os.Open(chainData.ConfDir + string(os.PathSeparator) + chainData.ConfName)
+	c, err := conf.Load(filepath.Join(chainData.ConfDir, chainData.ConfName), chainData.ConfDir)
 	if err != nil {
 		println(err.Error())
 		return err
 	}
-
-	fileScanner := bufio.NewScanner(readFile)
-	fileScanner.Split(bufio.ScanLines)
-	var fileLines []string
-
-	for fileScanner.Scan() {
-		fileLines = append(fileLines, fileScanner.Text())
+	chainData.Network = c.Network()
+	chainData.NetDir = netDataDir(c, chainData)
+	chainData.RPCUser, _ = c.Get("rpcuser")
+	chainData.RPCPass, _ = c.Get("rpcpassword")
+	chainData.CookieFile = ""
+	if chainData.RPCPass == "" {
+		chainData.CookieFile = cookiePath(c, chainData)
 	}
-
-	readFile.Close()
-
-	confMap := make(map[string]interface{})
-
-	for _, line := range fileLines {
-		a := strings.Split(line, "=")
-		if len(a) == 2 {
-			k := strings.TrimSpace(a[0])
-			v := strings.TrimSpace(a[1])
-			// println(k + " = " + v)
-			if k != "" {
-				iv, err := (strconv.ParseInt(v, 0, 64))
-				if err != nil {
-					confMap[k] = v
-				} else {
-					confMap[k] = int(iv)
-				}
-			}
+	if v, ok := c.Get("rpcconnect"); ok {
+		chainData.RPCHost = v
+	}
+	for _, s := range []struct {
+		key    string
+		dst    *int
+		source *ValueSource
+	}{
+		{"rpcport", &chainData.Port, &chainData.PortSource},
+		{"slot", &chainData.Slot, &chainData.SlotSource},
+	} {
+		v, ok, err := c.GetInt(s.key)
+		if err != nil {
+			return err
+		}
+		if ok {
+			*s.dst, *s.source = v, SourceConf
 		}
 	}
-
-	jsonData, _ := json.Marshal(confMap)
-	err = json.Unmarshal(jsonData, &chainData)
-	if err != nil {
-		println(err.Error())
+	if v, ok, err := c.GetInt("port"); err != nil {
 		return err
+	} else if ok {
+		chainData.P2PPort, chainData.P2PSource = v, SourceConf
+	}
+	if v, ok := c.GetBool("refreshbmm"); ok {
+		chainData.RefreshBMM = v
+	}
+	if v, ok := c.GetBool("bmmfee"); ok {
+		chainData.BMMFee = v
 	}
 	return nil
 }
 
-func writeBinary(cd *ChainData) error {
-	var binBytes []byte
-	binDir := cd.BinDir + string(os.PathSeparator) + cd.BinName
-	target := runtime.GOOS
-	switch target {
-	case "linux":
-		switch cd.ID {
-		case "drivechain":
-			binBytes = drivechainLinux
-		case "testchain":
-			binBytes = testchainLinux
-		case "bitassets":
-			binBytes = bitassetsLinux
-		case "thunder":
-			binBytes = thunderLinux
-		case "latestcore":
-			binBytes = latestCoreLinux
-		case "bitnames":
-			return WriteBitnamesZipContents(cd)
-		}
-	}
-	if len(binBytes) > 0 {
-		err := os.WriteFile(binDir, binBytes, 0o755)
-		if err != nil {
-			return err
+// cookiePath returns where bitcoind writes its .cookie file for the conf's
+// network when no rpcpassword is set.
+func cookiePath(c *conf.Config, chainData *ChainData) string {
+	if v, ok := c.Get("rpccookiefile"); ok {
+		if filepath.IsAbs(v) {
+			return v
 		}
+		return filepath.Join(chainData.NetDir, v)
 	}
+	return filepath.Join(chainData.NetDir, ".cookie")
+}
 
-	return nil
+// netDataDir returns the directory bitcoind keeps the conf's network in,
+// main is the datadir itself.
+func netDataDir(c *conf.Config, chainData *ChainData) string {
+	dir := chainData.ConfDir
+	if v, ok := c.Get("datadir"); ok {
+		dir = v
+	}
+	switch net := c.Network(); net {
+	case "main":
+		return dir
+	case "test":
+		return filepath.Join(dir, "testnet3")
+	default:
+		return filepath.Join(dir, net)
+	}
 }
 
 func IsDirEmpty(name string) (bool, error) {
@@ -309,48 +284,3 @@ func IsDirEmpty(name string) (bool, error) {
 	}
 	return false, err
 }
-
-func WriteBitnamesZipContents(cd *ChainData) error {
-	dst := cd.ConfDir
-	reader := bytes.NewReader(bitnamesZipLinux)
-	zipReader, err := zip.NewReader(reader, int64(len(bitnamesZipLinux)))
-	if err != nil {
-		return err
-	}
-	for _, f := range zipReader.File {
-		filePath := filepath.Join(dst, f.Name)
-		// fmt.Println("unzipping file ", filePath)
-
-		if !strings.HasPrefix(filePath, filepath.Clean(dst)+string(os.PathSeparator)) {
-			fmt.Println("invalid file path")
-			return err
-		}
-		if f.FileInfo().IsDir() {
-			// fmt.Println("creating directory...")
-			os.MkdirAll(filePath, os.ModePerm)
-			continue
-		}
-
-		if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
-			panic(err)
-		}
-
-		dstFile, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-		if err != nil {
-			panic(err)
-		}
-
-		fileInArchive, err := f.Open()
-		if err != nil {
-			panic(err)
-		}
-
-		if _, err := io.Copy(dstFile, fileInArchive); err != nil {
-			panic(err)
-		}
-
-		dstFile.Close()
-		fileInArchive.Close()
-	}
-	return nil
-}
@@ -0,0 +1,280 @@
+// Package conf reads and edits bitcoin.conf style files. Files keep their
+// comments, blank lines and ordering, so a file can be written back with
+// only the changed settings differing.
+package conf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// line is one line of a file. Comments, blank lines and section headers
+// have no key.
+type line struct {
+	text    string // As read, or as last written by Set
+	section string // Network the setting applies to, "" for the default section
+	name    string // Key as written, e.g. regtest.rpcport
+	key     string
+	value   string
+	comment string // Trailing comment, including the #
+	header  string // Section name if the line is a section header
+}
+
+// File is a parsed conf file.
+type File struct {
+	lines []*line
+}
+
+// ParseError is returned for lines bitcoind would refuse to start with.
+type ParseError struct {
+	Line int
+	Text string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %q is not key=value", e.Line, e.Text)
+}
+
+// Parse parses the contents of a conf file. Like bitcoind, everything after
+// a # is a comment and only the first = separates the key from the value.
+func Parse(b []byte) (*File, error) {
+	f := &File{}
+	section := ""
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; scanner.Scan(); n++ {
+		l := &line{text: strings.TrimSuffix(scanner.Text(), "\r")}
+		content := l.text
+		if i := strings.IndexByte(content, '#'); i >= 0 {
+			content, l.comment = content[:i], content[i:]
+		}
+		content = strings.TrimSpace(content)
+		switch {
+		case content == "":
+		case strings.HasPrefix(content, "[") && strings.HasSuffix(content, "]"):
+			section = strings.TrimSpace(content[1 : len(content)-1])
+			l.header = section
+		default:
+			k, v, ok := strings.Cut(content, "=")
+			if !ok {
+				return nil, &ParseError{Line: n, Text: content}
+			}
+			l.name = strings.TrimSpace(k)
+			l.key = l.name
+			l.value = strings.TrimSpace(v)
+			l.section = section
+			// regtest.rpcport=1 in the default section applies to regtest
+			if net, key, ok := strings.Cut(l.name, "."); ok && section == "" {
+				l.section, l.key = net, key
+			}
+		}
+		f.lines = append(f.lines, l)
+	}
+	return f, scanner.Err()
+}
+
+func ReadFile(path string) (*File, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Get returns the first value of key in section, "" being the default
+// section. bitcoind uses the first value if a key is repeated.
+func (f *File) Get(section string, key string) (string, bool) {
+	values := f.GetAll(section, key)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// GetAll returns every value of a repeated key such as addnode in section.
+func (f *File) GetAll(section string, key string) []string {
+	var values []string
+	for _, l := range f.lines {
+		if l.key == key && l.section == section {
+			values = append(values, l.value)
+		}
+	}
+	return values
+}
+
+// Set replaces the values of key in section with value. The first value is
+// changed in place, keeping its comment, and any others are removed. A new
+// key is added at the end of the section, which is created if needed.
+func (f *File) Set(section string, key string, value string) error {
+	if err := checkSetting(key, value); err != nil {
+		return err
+	}
+	found := false
+	lines := f.lines[:0]
+	for _, l := range f.lines {
+		if l.key == key && l.section == section {
+			if found {
+				continue
+			}
+			found = true
+			l.set(value)
+		}
+		lines = append(lines, l)
+	}
+	f.lines = lines
+	if !found {
+		f.insert(section, key, value)
+	}
+	return nil
+}
+
+// Add adds another value for a repeated key such as addnode or connect.
+func (f *File) Add(section string, key string, value string) error {
+	if err := checkSetting(key, value); err != nil {
+		return err
+	}
+	f.insert(section, key, value)
+	return nil
+}
+
+// Unset removes every value of key in section.
+func (f *File) Unset(section string, key string) {
+	lines := f.lines[:0]
+	for _, l := range f.lines {
+		if l.key != key || l.section != section {
+			lines = append(lines, l)
+		}
+	}
+	f.lines = lines
+}
+
+// RemoveSection removes the header of section if no settings are left in it.
+func (f *File) RemoveSection(section string) {
+	if section == "" || len(f.Keys(section)) > 0 {
+		return
+	}
+	lines := f.lines[:0]
+	for _, l := range f.lines {
+		if l.header != section {
+			lines = append(lines, l)
+			continue
+		}
+		// Along with the blank lines insert put before it
+		for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1].text) == "" {
+			lines = lines[:len(lines)-1]
+		}
+	}
+	f.lines = lines
+}
+
+// Keys returns the keys set in section, in the order they first appear.
+func (f *File) Keys(section string) []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, l := range f.lines {
+		if l.key != "" && l.section == section && !seen[l.key] {
+			seen[l.key] = true
+			keys = append(keys, l.key)
+		}
+	}
+	return keys
+}
+
+func checkSetting(key string, value string) error {
+	if key == "" || strings.ContainsAny(key, "=#[]. \t\r\n") {
+		return fmt.Errorf("invalid conf key %q", key)
+	}
+	// Would be cut off as a comment or a new line when read back
+	if strings.ContainsAny(value, "#\r\n") {
+		return fmt.Errorf("%s: value can't contain # or line breaks", key)
+	}
+	return nil
+}
+
+func (l *line) set(value string) {
+	l.value = value
+	l.text = l.name + "=" + value
+	if l.comment != "" {
+		l.text += " " + l.comment
+	}
+}
+
+// insert adds key=value after the last setting in section.
+func (f *File) insert(section string, key string, value string) {
+	l := &line{section: section, name: key, key: key}
+	l.set(value)
+
+	// The section runs from its header, or the start of the file for the
+	// default section, to the next header
+	start, end := -1, len(f.lines)
+	if section == "" {
+		start = 0
+	}
+	for i, fl := range f.lines {
+		if fl.header == "" {
+			continue
+		}
+		if start >= 0 {
+			end = i
+			break
+		}
+		if fl.header == section {
+			start = i + 1
+		}
+	}
+	if start < 0 {
+		if n := len(f.lines); n > 0 && strings.TrimSpace(f.lines[n-1].text) != "" {
+			f.lines = append(f.lines, &line{})
+		}
+		f.lines = append(f.lines, &line{text: "[" + section + "]", header: section}, l)
+		return
+	}
+	// After the last non blank line, so blank lines before the next header
+	// stay there
+	at := end
+	for at > start && strings.TrimSpace(f.lines[at-1].text) == "" {
+		at--
+	}
+	f.lines = append(f.lines[:at], append([]*line{l}, f.lines[at:]...)...)
+}
+
+// Bytes returns the file as it would be written.
+func (f *File) Bytes() []byte {
+	var b bytes.Buffer
+	for _, l := range f.lines {
+		b.WriteString(l.text)
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// WriteFile writes the file to path, replacing it in one step so a chain
+// never reads a half written conf.
+func (f *File) WriteFile(path string, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(f.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if fi, err := os.Stat(path); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,286 @@
+package conf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const sample = `# Written by hand
+server=1
+rpcuser=user # the user
+addnode=a
+addnode=b
+
+[regtest]
+rpcport=18443
+`
+
+func TestParseRoundTrip(t *testing.T) {
+	for _, in := range []string{
+		"",
+		sample,
+		"regtest.rpcport=1\n\n\n[signet]\n# nothing\n",
+		"key=value=with=equals\nempty=\n",
+	} {
+		f, err := Parse([]byte(in))
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", in, err)
+		}
+		if got := string(f.Bytes()); got != in {
+			t.Errorf("Parse(%q).Bytes() = %q", in, got)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		line int
+	}{
+		{"server\n", 1},
+		{"server=1\n\n[regtest]\nrpcport 1\n", 4},
+		{"# ok\n  junk # comment\n", 2},
+	} {
+		_, err := Parse([]byte(tt.in))
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("Parse(%q) = %v, want a ParseError", tt.in, err)
+			continue
+		}
+		if pe.Line != tt.line {
+			t.Errorf("Parse(%q) error on line %d, want %d", tt.in, pe.Line, tt.line)
+		}
+	}
+}
+
+func TestFileGet(t *testing.T) {
+	f, err := Parse([]byte("test.rpcport=18332\n" + sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		section, key string
+		want         string
+		ok           bool
+	}{
+		{"", "server", "1", true},
+		{"", "rpcuser", "user", true},
+		{"", "addnode", "a", true},
+		{"regtest", "rpcport", "18443", true},
+		// test.rpcport applies to the test network wherever it is written
+		{"test", "rpcport", "18332", true},
+		{"", "rpcport", "", false},
+		{"regtest", "server", "", false},
+	} {
+		got, ok := f.Get(tt.section, tt.key)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Get(%q, %q) = %q, %v, want %q, %v", tt.section, tt.key, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFileEdit(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		edit func(f *File) error
+		want string
+	}{
+		{"set keeps the comment", func(f *File) error {
+			return f.Set("", "rpcuser", "other")
+		}, `# Written by hand
+server=1
+rpcuser=other # the user
+addnode=a
+addnode=b
+
+[regtest]
+rpcport=18443
+`},
+		{"set replaces repeated values", func(f *File) error {
+			return f.Set("", "addnode", "c")
+		}, `# Written by hand
+server=1
+rpcuser=user # the user
+addnode=c
+
+[regtest]
+rpcport=18443
+`},
+		{"new key goes at the end of its section", func(f *File) error {
+			return f.Set("", "txindex", "1")
+		}, `# Written by hand
+server=1
+rpcuser=user # the user
+addnode=a
+addnode=b
+txindex=1
+
+[regtest]
+rpcport=18443
+`},
+		{"new section", func(f *File) error {
+			return f.Set("signet", "rpcport", "38332")
+		}, sample + `
+[signet]
+rpcport=38332
+`},
+		{"add", func(f *File) error {
+			return f.Add("regtest", "addnode", "c")
+		}, `# Written by hand
+server=1
+rpcuser=user # the user
+addnode=a
+addnode=b
+
+[regtest]
+rpcport=18443
+addnode=c
+`},
+		{"unset", func(f *File) error {
+			f.Unset("", "addnode")
+			return nil
+		}, `# Written by hand
+server=1
+rpcuser=user # the user
+
+[regtest]
+rpcport=18443
+`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse([]byte(sample))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.edit(f); err != nil {
+				t.Fatal(err)
+			}
+			if got := string(f.Bytes()); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+			// What was written reads back the same
+			again, err := Parse(f.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(again.Bytes()); got != tt.want {
+				t.Errorf("read back as\n%s", got)
+			}
+		})
+	}
+}
+
+func TestSetRejectsInvalidSettings(t *testing.T) {
+	for _, tt := range []struct{ key, value string }{
+		{"", "1"},
+		{"a=b", "1"},
+		{"regtest.rpcport", "1"},
+		{"rpc user", "1"},
+		{"rpcuser", "a#b"},
+		{"rpcuser", "a\nb"},
+	} {
+		f := &File{}
+		if err := f.Set("", tt.key, tt.value); err == nil {
+			t.Errorf("Set(%q, %q) succeeded", tt.key, tt.value)
+		}
+	}
+}
+
+func TestConfigGet(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, s string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(s), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("extra.conf", "rpcuser=included\ntxindex=1\n[regtest]\nrpcport=1\n")
+	path := write("bitcoin.conf", `regtest=1
+includeconf=extra.conf
+rpcuser=main
+server=1
+[regtest]
+rpcport=18443
+`)
+	c, err := Load(path, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if net := c.Network(); net != "regtest" {
+		t.Errorf("Network() = %q, want regtest", net)
+	}
+	for _, tt := range []struct {
+		key  string
+		want string
+		ok   bool
+	}{
+		// The main file comes before the included one
+		{"rpcuser", "main", true},
+		{"rpcport", "18443", true},
+		{"txindex", "1", true},
+		{"server", "1", true},
+		{"rpcpassword", "", false},
+	} {
+		got, ok := c.Get(tt.key)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Get(%q) = %q, %v, want %q, %v", tt.key, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestConfigNetwork(t *testing.T) {
+	for _, tt := range []struct{ conf, want string }{
+		{"", "main"},
+		{"regtest=1\n", "regtest"},
+		{"regtest=0\n", "main"},
+		{"testnet=1\n", "test"},
+		{"signet=\n", "signet"},
+		{"chain=testnet4\nregtest=1\n", "testnet4"},
+	} {
+		f, err := Parse([]byte(tt.conf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := &Config{Files: []*File{f}}
+		if got := c.Network(); got != tt.want {
+			t.Errorf("Network() of %q = %q, want %q", tt.conf, got, tt.want)
+		}
+	}
+}
+
+func TestConfigGetNetworkOnly(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		conf string
+		key  string
+		want string
+		ok   bool
+	}{
+		{"main reads the default section", "rpcport=1\n", "rpcport", "1", true},
+		{"main section first", "rpcport=1\n[main]\nrpcport=2\n", "rpcport", "2", true},
+		{"ignored outside the network section", "regtest=1\nrpcport=1\n", "rpcport", "", false},
+		{"network section", "regtest=1\nrpcport=1\n[regtest]\nrpcport=2\n", "rpcport", "2", true},
+		{"network prefix", "regtest=1\nregtest.port=3\n", "port", "3", true},
+		{"other network's section", "signet=1\n[regtest]\nrpcport=2\n", "rpcport", "", false},
+		{"addnode", "regtest=1\naddnode=a\n", "addnode", "", false},
+		{"other keys fall back", "regtest=1\nrpcuser=u\n", "rpcuser", "u", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse([]byte(tt.conf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := &Config{Files: []*File{f}}
+			got, ok := c.Get(tt.key)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Get(%q) = %q, %v, want %q, %v", tt.key, got, ok, tt.want, tt.ok)
+			}
+			if all := c.GetAll(tt.key); (len(all) > 0) != tt.ok {
+				t.Errorf("GetAll(%q) = %q", tt.key, all)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package conf
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+)
+
+// Config is a conf file together with the files it includes, read the way
+// bitcoind reads them: settings in the network's section come before the
+// default section and within those the first value wins.
+type Config struct {
+	Path string
+	// The main file first, then the files from includeconf in order
+	Files []*File
+}
+
+// Load reads the conf file at path and the files it includes. Relative
+// includeconf paths are relative to datadir.
+func Load(path string, datadir string) (*Config, error) {
+	main, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{Path: path, Files: []*File{main}}
+	// Included files can't include further files
+	for _, inc := range c.GetAll("includeconf") {
+		if !filepath.IsAbs(inc) {
+			inc = filepath.Join(datadir, inc)
+		}
+		f, err := ReadFile(inc)
+		if err != nil {
+			return nil, fmt.Errorf("includeconf in %s: %w", path, err)
+		}
+		c.Files = append(c.Files, f)
+	}
+	return c, nil
+}
+
+// Main returns the file at Path, the one to edit.
+func (c *Config) Main() *File {
+	return c.Files[0]
+}
+
+// Network returns the network the conf selects and the name of its section:
+// main, test, testnet4, signet or regtest.
+func (c *Config) Network() string {
+	if chain, ok := c.first("", "chain"); ok {
+		return chain
+	}
+	for _, net := range []struct{ flag, section string }{
+		{"regtest", "regtest"},
+		{"testnet", "test"},
+		{"testnet4", "testnet4"},
+		{"signet", "signet"},
+	} {
+		if v, ok := c.first("", net.flag); ok && parseBool(v) {
+			return net.section
+		}
+	}
+	return "main"
+}
+
+// first returns the first value of key in section across all files.
+func (c *Config) first(section string, key string) (string, bool) {
+	for _, f := range c.Files {
+		if v, ok := f.Get(section, key); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// networkOnly are the keys bitcoind only reads from the network's section
+// on networks other than main, e.g. an rpcport in the default section is
+// ignored on regtest.
+var networkOnly = map[string]bool{
+	"addnode": true,
+	"bind":    true,
+	"connect": true,
+	"port":    true,
+	"rpcbind": true,
+	"rpcport": true,
+	"wallet":  true,
+}
+
+// NetworkOnly reports whether bitcoind reads key only from the network's
+// section on networks other than main.
+func NetworkOnly(key string) bool {
+	return networkOnly[key]
+}
+
+// sections returns the sections key is read from for the conf's network,
+// in order of precedence.
+func (c *Config) sections(key string) []string {
+	net := c.Network()
+	if net == "main" {
+		return []string{"main", ""}
+	}
+	if networkOnly[key] {
+		return []string{net}
+	}
+	return []string{net, ""}
+}
+
+// Get returns the value of key for the conf's network.
+func (c *Config) Get(key string) (string, bool) {
+	for _, section := range c.sections(key) {
+		if v, ok := c.first(section, key); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// GetAll returns every value of a repeated key such as addnode for the
+// conf's network.
+func (c *Config) GetAll(key string) []string {
+	var values []string
+	for _, section := range c.sections(key) {
+		for _, f := range c.Files {
+			values = append(values, f.GetAll(section, key)...)
+		}
+	}
+	return values
+}
+
+// GetInt returns the value of key as an int.
+func (c *Config) GetInt(key string) (int, bool, error) {
+	v, ok := c.Get(key)
+	if !ok {
+		return 0, false, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, false, fmt.Errorf("%s: %s=%s is not a number", c.Path, key, v)
+	}
+	return i, true, nil
+}
+
+// GetBool returns the value of a flag, nokey=1 turns key off.
+func (c *Config) GetBool(key string) (bool, bool) {
+	if v, ok := c.Get(key); ok {
+		return parseBool(v), true
+	}
+	if v, ok := c.Get("no" + key); ok {
+		return !parseBool(v), true
+	}
+	return false, false
+}
+
+// parseBool interprets a flag like bitcoind, an empty value turns it on.
+func parseBool(v string) bool {
+	if v == "" {
+		return true
+	}
+	i, err := strconv.Atoi(v)
+	return err == nil && i != 0
+}
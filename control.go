@@ -0,0 +1,366 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Controller is everything the UI and CLI can ask the launcher core to do.
+// It is implemented in process by localController and over the daemon's
+// control socket by ControlClient.
+type Controller interface {
+	Providers() (map[string]ChainProvider, error)
+	Status() ([]ChainStatus, error)
+	Start(id string) error
+	Stop(id string, progress StopProgress) error
+	// Update installs the catalogue version of a chain and switches to it
+	Update(id string) error
+	// UseVersion switches a chain to an installed version
+	UseVersion(id string, version string) error
+	// Settings returns the conf settings of a chain
+	Settings(id string) (ChainSettings, error)
+	// SaveSettings writes the conf settings of a chain, which apply the next
+	// time it starts
+	SaveSettings(id string, s ChainSettings) error
+	// Verify checks the installed files of a chain
+	Verify(id string) (VerifyReport, error)
+	// Network returns the network chains run on
+	Network() (Network, error)
+	// SetNetwork switches the launcher to another network, running chains
+	// switch when they are restarted
+	SetNetwork(n Network) error
+	// Ports checks the ports of every chain for conflicts
+	Ports() ([]PortCheck, error)
+	// FixPorts moves chains off ports they can't get, all chains if ids is
+	// empty
+	FixPorts(ids []string) ([]PortChange, error)
+	Mine(blocks int) error
+	SetAutomine(enabled bool) error
+	Reset() error
+	// ResetChain deletes the chain data, the wallet or everything of a
+	// chain, or lists what would be deleted
+	ResetChain(id string, opts ResetOptions) (ResetReport, error)
+	// BackupWallet archives the wallet of a chain to the backups directory
+	BackupWallet(id string, opts BackupOptions) (WalletBackup, error)
+	// WalletBackups lists the wallet backups of a chain, newest first
+	WalletBackups(id string) ([]WalletBackup, error)
+	// RestoreWallet replaces the wallet of a chain with a backup and
+	// returns the backup of the wallet it replaced
+	RestoreWallet(id string, opts RestoreOptions) (WalletBackup, error)
+}
+
+type ChainStatus struct {
+	ID               string      `json:"id"`
+	Name             string      `json:"name"`
+	State            State       `json:"state"`
+	Network          string      `json:"network,omitempty"`
+	PID              int         `json:"pid,omitempty"`
+	Height           int         `json:"height"`
+	AvailableBalance float64     `json:"availablebalance"`
+	PendingBalance   float64     `json:"pendingbalance"`
+	MempoolSize      int         `json:"mempoolsize"`
+	BestBlockHash    string      `json:"bestblockhash,omitempty"`
+	RPCPort          int         `json:"rpcport"`
+	RPCPortSource    ValueSource `json:"rpcportsource"`
+	Slot             int         `json:"slot,omitempty"`
+	SlotSource       ValueSource `json:"slotsource,omitempty"`
+	Automine         bool        `json:"automine,omitempty"`
+	CrashCount       int         `json:"crashcount"`
+	LastExitCode     int         `json:"lastexitcode"`
+
+	Download *DownloadProgress `json:"download,omitempty"`
+	Versions VersionInfo       `json:"versions"`
+}
+
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *State) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "waiting":
+		*s = Waiting
+	case "running":
+		*s = Running
+	default:
+		*s = Unknown
+	}
+	return nil
+}
+
+// chainByID looks up the chain data and a copy of the state for id.
+func chainByID(as *AppState, id string) (*ChainData, *ChainState, error) {
+	cd, ok := as.chainData(id)
+	if !ok {
+		var ids []string
+		for k := range as.cp {
+			ids = append(ids, k)
+		}
+		sort.Strings(ids)
+		return nil, nil, fmt.Errorf("unknown chain %q, expected one of: %s", id, strings.Join(ids, ", "))
+	}
+	cs, _ := as.chainState(id)
+	return &cd, &cs, nil
+}
+
+// drivechainID returns the id of the drivechain provider.
+func drivechainID(providers map[string]ChainProvider) string {
+	for id, cp := range providers {
+		if kind, _, _ := DriverFor(cp); kind == KindDrivechain {
+			return id
+		}
+	}
+	return ""
+}
+
+// StartChainByID launches a chain, proposing and activating sidechains on
+// the drivechain first if needed.
+func StartChainByID(as *AppState, id string) error {
+	cd, cs, err := chainByID(as, id)
+	if err != nil {
+		return err
+	}
+
+	// Pick up changes made to the conf since the launcher started
+	if err := as.loadChainConf(cd); err != nil {
+		return err
+	}
+	if err := as.switchNetwork(cd); err != nil {
+		return err
+	}
+
+	if !cd.IsDrivechain {
+		dcd, dcs := as.drivechainData(), as.drivechainState()
+		if dcs.State != Running {
+			PollChainState(&dcd, &dcs)
+		}
+		if dcs.State != Running {
+			return fmt.Errorf("drivechain is not running, start it before %s", id)
+		}
+		if usesNetworkConf(cd) && cd.Network != dcd.Network {
+			return fmt.Errorf("drivechain runs on %s, restart it to switch it to %s", dcd.Network, cd.Network)
+		}
+		// A drivechain using a cookie has new credentials every start
+		secureChainCredentials(as, cd)
+		if cd.Kind == KindBitnames {
+			if err := pointAtMainchain(cd, dcd.Port); err != nil {
+				return err
+			}
+		}
+		needsActivation, err := NeedsActivation(cd, as)
+		if err != nil {
+			println(err.Error())
+		}
+		if needsActivation {
+			if err := CreateSidechainProposal(as, cd, cs); err != nil {
+				return fmt.Errorf("could not activate %s: %w", id, err)
+			}
+		}
+	}
+
+	as.setChainData(cd)
+	return LaunchChain(cd, cs, as)
+}
+
+// chainIDs returns the ids of every chain, drivechain first and sidechains
+// ordered by slot.
+func chainIDs(as *AppState) []string {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	var sidechains []string
+	for k := range as.scd {
+		sidechains = append(sidechains, k)
+	}
+	sort.Slice(sidechains, func(i, j int) bool {
+		return as.scd[sidechains[i]].Slot < as.scd[sidechains[j]].Slot
+	})
+	return append([]string{as.dcd.ID}, sidechains...)
+}
+
+// ChainStatuses returns the state of every chain, drivechain first and
+// sidechains ordered by slot. With poll set every chain is queried once,
+// otherwise the state kept up to date by the pollers is used.
+func ChainStatuses(as *AppState, poll bool) []ChainStatus {
+	var statuses []ChainStatus
+	for _, id := range chainIDs(as) {
+		cd, cs, err := chainByID(as, id)
+		if err != nil {
+			continue
+		}
+		drv := cd.Driver()
+		if poll {
+			drv.HealthCheck(cd, cs)
+		}
+		st := *cs
+		ps := as.sup.Status(id)
+		pid := 0
+		if ps.Running {
+			pid = ps.PID
+		}
+		if ps.Running && !drv.SupportsRPC() {
+			st.State = Running
+		} else if ps.Running && st.State == Unknown {
+			st.State = Waiting
+		}
+		statuses = append(statuses, ChainStatus{
+			ID:               id,
+			Name:             as.cp[id].Name,
+			State:            st.State,
+			Network:          cd.Network,
+			PID:              pid,
+			Height:           st.Height,
+			AvailableBalance: st.AvailableBalance,
+			PendingBalance:   st.PendingBalance,
+			MempoolSize:      st.MempoolSize,
+			BestBlockHash:    st.BestBlockHash,
+			RPCPort:          cd.Port,
+			RPCPortSource:    cd.PortSource,
+			Slot:             cd.Slot,
+			SlotSource:       cd.SlotSource,
+			Automine:         st.Automine,
+			CrashCount:       st.CrashCount,
+			LastExitCode:     st.LastExitCode,
+			Download:         as.download(id),
+			Versions:         ChainVersionInfo(cd),
+		})
+	}
+	return statuses
+}
+
+// localController runs everything inside this process.
+type localController struct {
+	as *AppState
+	// Query the nodes on every Status call, for one shot CLI commands
+	// where no pollers are running
+	poll bool
+}
+
+func NewLocalController(as *AppState, poll bool) Controller {
+	return &localController{as: as, poll: poll}
+}
+
+func (lc *localController) Providers() (map[string]ChainProvider, error) {
+	return lc.as.cp, nil
+}
+
+func (lc *localController) Status() ([]ChainStatus, error) {
+	return ChainStatuses(lc.as, lc.poll), nil
+}
+
+func (lc *localController) Start(id string) error {
+	return StartChainByID(lc.as, id)
+}
+
+func (lc *localController) Stop(id string, progress StopProgress) error {
+	cd, cs, err := chainByID(lc.as, id)
+	if err != nil {
+		return err
+	}
+	if cd.IsDrivechain {
+		lc.as.setAutomine(false)
+	}
+	return StopChain(cd, cs, lc.as, progress)
+}
+
+func (lc *localController) Update(id string) error {
+	return UpdateChain(lc.as, id)
+}
+
+func (lc *localController) UseVersion(id string, version string) error {
+	return UseChainVersion(lc.as, id, version)
+}
+
+func (lc *localController) Settings(id string) (ChainSettings, error) {
+	cd, _, err := chainByID(lc.as, id)
+	if err != nil {
+		return ChainSettings{}, err
+	}
+	return LoadChainSettings(cd)
+}
+
+func (lc *localController) SaveSettings(id string, s ChainSettings) error {
+	cd, _, err := chainByID(lc.as, id)
+	if err != nil {
+		return err
+	}
+	if err := SaveChainSettings(cd, s); err != nil {
+		return err
+	}
+	// A running chain keeps its settings until it is restarted
+	if lc.as.sup.IsRunning(id) {
+		return nil
+	}
+	if err := lc.as.loadChainConf(cd); err != nil {
+		return err
+	}
+	lc.as.setChainData(cd)
+	return nil
+}
+
+func (lc *localController) Verify(id string) (VerifyReport, error) {
+	cd, _, err := chainByID(lc.as, id)
+	if err != nil {
+		return VerifyReport{ID: id}, err
+	}
+	return VerifyChain(cd)
+}
+
+func (lc *localController) Network() (Network, error) {
+	return lc.as.network, nil
+}
+
+func (lc *localController) SetNetwork(n Network) error {
+	if n != Regtest {
+		lc.as.setAutomine(false)
+	}
+	return SetNetwork(lc.as, n)
+}
+
+func (lc *localController) Ports() ([]PortCheck, error) {
+	return CheckPorts(lc.as), nil
+}
+
+func (lc *localController) FixPorts(ids []string) ([]PortChange, error) {
+	return FixPorts(lc.as, ids)
+}
+
+func (lc *localController) Mine(blocks int) error {
+	return DrivechainMine(lc.as, blocks)
+}
+
+func (lc *localController) SetAutomine(enabled bool) error {
+	dcd := lc.as.drivechainData()
+	if enabled && !canGenerate(&dcd) {
+		return errNotRegtest
+	}
+	lc.as.setAutomine(enabled)
+	lc.as.Refresh()
+	return nil
+}
+
+func (lc *localController) Reset() error {
+	return ResetEverything(lc.as)
+}
+
+func (lc *localController) ResetChain(id string, opts ResetOptions) (ResetReport, error) {
+	if id == lc.as.drivechainData().ID && !opts.DryRun {
+		lc.as.setAutomine(false)
+	}
+	return ResetChain(lc.as, id, opts)
+}
+
+func (lc *localController) BackupWallet(id string, opts BackupOptions) (WalletBackup, error) {
+	return BackupWallet(lc.as, id, opts)
+}
+
+func (lc *localController) WalletBackups(id string) ([]WalletBackup, error) {
+	return WalletBackups(lc.as, id)
+}
+
+func (lc *localController) RestoreWallet(id string, opts RestoreOptions) (WalletBackup, error) {
+	if id == lc.as.drivechainData().ID {
+		lc.as.setAutomine(false)
+	}
+	return RestoreWallet(lc.as, id, opts)
+}
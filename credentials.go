@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// weakRPCPassword is the password chain.conf ships with. Chains still using
+// it get a random one the first time the launcher sees them stopped.
+const weakRPCPassword = "password"
+
+func randomPassword() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// secureCredentials replaces the shipped RPC password of every chain that
+// isn't running. Sidechains talk to drivechain with their own rpcuser and
+// rpcpassword, so they always get drivechain's. Chains using cookie
+// authentication are left alone.
+func secureCredentials(as *AppState) {
+	chains := append([]ChainData{as.drivechainData()}, as.sidechains()...)
+	for _, cd := range chains {
+		if cd.ID == "" {
+			continue
+		}
+		secureChainCredentials(as, &cd)
+		as.setChainData(&cd)
+	}
+}
+
+// secureChainCredentials replaces the shipped RPC password of cd, and gives
+// a sidechain the credentials drivechain actually uses.
+func secureChainCredentials(as *AppState, cd *ChainData) {
+	if cd.ID == "" {
+		return
+	}
+	switch cd.Kind {
+	case KindBitcoinSidechain, KindBitnames:
+		dcd := as.drivechainData()
+		if dcd.ID == "" {
+			break
+		}
+		user, pass, err := dcd.Credentials()
+		if err != nil || pass == "" {
+			// Cookie of a stopped drivechain, there is nothing to copy yet
+			break
+		}
+		if cd.RPCUser != user || cd.RPCPass != pass {
+			setCredentials(as, cd, user, pass)
+		}
+		return
+	}
+	if cd.RPCPass != weakRPCPassword {
+		return
+	}
+	pass, err := randomPassword()
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	setCredentials(as, cd, cd.RPCUser, pass)
+}
+
+// setCredentials writes user and pass to the conf file of cd. A running
+// chain keeps its credentials, it would no longer accept the launcher's RPC
+// calls. Like a reset, this counts a chain as running if it has a live
+// process, holds its data directory or answers RPC calls.
+func setCredentials(as *AppState, cd *ChainData, user string, pass string) {
+	if chainProcessAlive(as, cd) || chainAnswers(cd) {
+		println(cd.ID + " is running, restart it to change its RPC credentials")
+		return
+	}
+	s, err := LoadChainSettings(cd)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	s.RPCUser, s.RPCPassword = user, pass
+	if err := SaveChainSettings(cd, s); err != nil {
+		println(err.Error())
+		return
+	}
+	println("Wrote RPC credentials for " + cd.ID)
+	cd.RPCUser, cd.RPCPass, cd.CookieFile = user, pass, ""
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSecureChainCredentials(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		kind     ChainKind
+		pass     string
+		running  bool
+		wantUser string
+		wantPass string // "*" for a random one
+	}{
+		{"sidechain gets drivechain's", KindBitcoinSidechain, weakRPCPassword, false, "dc", "secret"},
+		{"even with its own password", KindBitnames, "mine", false, "dc", "secret"},
+		{"running sidechain", KindBitcoinSidechain, "mine", true, "user", "mine"},
+		{"weak password", KindThunder, weakRPCPassword, false, "user", "*"},
+		{"weak password while running", KindThunder, weakRPCPassword, true, "user", weakRPCPassword},
+		{"own password", KindThunder, "mine", false, "user", "mine"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			as := NewAppState()
+			as.setChainData(&ChainData{ID: "drivechain", IsDrivechain: true, RPCUser: "dc", RPCPass: "secret"})
+			dir := t.TempDir()
+			// A port nothing answers on
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			port := l.Addr().(*net.TCPAddr).Port
+			l.Close()
+			cd := &ChainData{
+				ID:       "testchain",
+				Kind:     tt.kind,
+				ConfDir:  dir,
+				ConfName: "testchain.conf",
+				RPCUser:  "user",
+				RPCPass:  tt.pass,
+				Port:     port,
+			}
+			conf := "rpcuser=user\nrpcpassword=" + tt.pass + "\n"
+			if err := os.WriteFile(filepath.Join(dir, cd.ConfName), []byte(conf), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if tt.running {
+				if _, err := as.sup.Adopt(cd.ID, os.Getpid()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			secureChainCredentials(as, cd)
+			s, err := LoadChainSettings(cd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.RPCUser != tt.wantUser || s.RPCUser != cd.RPCUser {
+				t.Errorf("rpcuser = %q in the conf, %q in memory, want %q", s.RPCUser, cd.RPCUser, tt.wantUser)
+			}
+			if tt.wantPass == "*" {
+				if s.RPCPassword == tt.pass || len(s.RPCPassword) != 64 {
+					t.Errorf("rpcpassword = %q, want a random one", s.RPCPassword)
+				}
+			} else if s.RPCPassword != tt.wantPass {
+				t.Errorf("rpcpassword = %q, want %q", s.RPCPassword, tt.wantPass)
+			}
+			if s.RPCPassword != cd.RPCPass {
+				t.Errorf("rpcpassword = %q in memory, %q in the conf", cd.RPCPass, s.RPCPassword)
+			}
+		})
+	}
+}
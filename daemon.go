@@ -0,0 +1,665 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	controlSocketName = "launcher.sock"
+	daemonLogName     = "daemon.log"
+)
+
+// controlSocketPath is the unix socket the daemon listens on, inside the
+// launcher directory.
+func controlSocketPath() (string, error) {
+	return nodeSocketPath(node)
+}
+
+// listenControlSocket listens on sock, which only the user may connect to.
+// Listen creates the socket with the umask's permissions before it can be
+// chmodded, so the directory it is in is restricted to the user first.
+func listenControlSocket(sock string) (net.Listener, error) {
+	dir := filepath.Dir(sock)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(dir, 0o700); err != nil {
+		return nil, err
+	}
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(sock, 0o600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// RunDaemon serves the control API on the unix socket until the process is
+// interrupted. Chains keep running when the daemon exits.
+func RunDaemon(as *AppState) error {
+	sock, err := controlSocketPath()
+	if err != nil {
+		return err
+	}
+
+	if ControlSocketAlive(sock) {
+		return fmt.Errorf("a launcher daemon is already listening on %s", sock)
+	}
+	// Left behind by a daemon that did not shut down cleanly
+	os.Remove(sock)
+
+	l, err := listenControlSocket(sock)
+	if err != nil {
+		return err
+	}
+
+	AdoptRunningChains(as, true)
+
+	srv := &http.Server{Handler: newControlHandler(as)}
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	println("Launcher daemon listening on " + sock)
+	err = srv.Serve(l)
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	return err
+}
+
+type controlError struct {
+	Error  string       `json:"error"`
+	Launch *LaunchError `json:"launcherror,omitempty"`
+}
+
+type launchErrorJSON struct {
+	ChainID string          `json:"chainid"`
+	Kind    LaunchErrorKind `json:"kind"`
+	Path    string          `json:"path,omitempty"`
+	Port    int             `json:"port,omitempty"`
+	Fix     string          `json:"fix,omitempty"`
+	Err     string          `json:"err,omitempty"`
+}
+
+func (e *LaunchError) MarshalJSON() ([]byte, error) {
+	j := launchErrorJSON{ChainID: e.ChainID, Kind: e.Kind, Path: e.Path, Port: e.Port, Fix: e.Fix}
+	if e.Err != nil {
+		j.Err = e.Err.Error()
+	}
+	return json.Marshal(j)
+}
+
+func (e *LaunchError) UnmarshalJSON(b []byte) error {
+	var j launchErrorJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*e = LaunchError{ChainID: j.ChainID, Kind: j.Kind, Path: j.Path, Port: j.Port, Fix: j.Fix}
+	if j.Err != "" {
+		e.Err = errors.New(j.Err)
+	}
+	return nil
+}
+
+// stopEvent is one line of the streamed response to a stop request.
+type stopEvent struct {
+	Step  string `json:"step,omitempty"`
+	Done  bool   `json:"done,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		println(err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	ce := controlError{Error: err.Error()}
+	var le *LaunchError
+	if errors.As(err, &le) {
+		ce.Launch = le
+	}
+	writeJSON(w, http.StatusInternalServerError, ce)
+}
+
+func newControlHandler(as *AppState) http.Handler {
+	lc := NewLocalController(as, false)
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/v1/providers", func(w http.ResponseWriter, r *http.Request) {
+		providers, err := lc.Providers()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, providers)
+	})
+
+	mux.HandleFunc("/v1/status", func(w http.ResponseWriter, r *http.Request) {
+		statuses, err := lc.Status()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, statuses)
+	})
+
+	// /v1/chains/<id>/start, stop, update, use, verify, settings, reset,
+	// backup, backups and restore
+	mux.HandleFunc("/v1/chains/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/chains/"), "/")
+		if len(parts) != 2 {
+			http.NotFound(w, r)
+			return
+		}
+		id, action := parts[0], parts[1]
+		method := http.MethodPost
+		if action == "verify" || action == "backups" || action == "settings" && r.Method == http.MethodGet {
+			method = http.MethodGet
+		}
+		if r.Method != method {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		switch action {
+		case "start":
+			if err := lc.Start(id); err != nil {
+				writeError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, struct{}{})
+		case "stop":
+			// Stopping can take a while, stream every step so clients can
+			// show progress
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			enc := json.NewEncoder(w)
+			flusher, _ := w.(http.Flusher)
+			err := lc.Stop(id, func(step string) {
+				enc.Encode(stopEvent{Step: step})
+				if flusher != nil {
+					flusher.Flush()
+				}
+			})
+			done := stopEvent{Done: true}
+			if err != nil {
+				done.Error = err.Error()
+			}
+			enc.Encode(done)
+		case "update":
+			if err := lc.Update(id); err != nil {
+				writeError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, struct{}{})
+		case "use":
+			var req struct {
+				Version string `json:"version"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Version == "" {
+				http.Error(w, "expected {\"version\": \"...\"}", http.StatusBadRequest)
+				return
+			}
+			if err := lc.UseVersion(id, req.Version); err != nil {
+				writeError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, struct{}{})
+		case "verify":
+			report, err := lc.Verify(id)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, report)
+		case "settings":
+			if r.Method == http.MethodGet {
+				s, err := lc.Settings(id)
+				if err != nil {
+					writeError(w, err)
+					return
+				}
+				writeJSON(w, http.StatusOK, s)
+				return
+			}
+			var s ChainSettings
+			if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := lc.SaveSettings(id, s); err != nil {
+				writeError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, struct{}{})
+		case "reset":
+			var opts ResetOptions
+			if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			report, err := lc.ResetChain(id, opts)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, report)
+		case "backup":
+			var opts BackupOptions
+			if err := json.NewDecoder(r.Body).Decode(&opts); err != nil && err != io.EOF {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			backup, err := lc.BackupWallet(id, opts)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, backup)
+		case "backups":
+			backups, err := lc.WalletBackups(id)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, backups)
+		case "restore":
+			var opts RestoreOptions
+			if err := json.NewDecoder(r.Body).Decode(&opts); err != nil || opts.File == "" {
+				http.Error(w, "expected {\"file\": \"...\"}", http.StatusBadRequest)
+				return
+			}
+			replaced, err := lc.RestoreWallet(id, opts)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, replaced)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	mux.HandleFunc("/v1/network", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Network Network `json:"network"`
+		}
+		if r.Method == http.MethodPost {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			n, err := ParseNetwork(string(req.Network))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := lc.SetNetwork(n); err != nil {
+				writeError(w, err)
+				return
+			}
+		}
+		n, err := lc.Network()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		req.Network = n
+		writeJSON(w, http.StatusOK, req)
+	})
+
+	mux.HandleFunc("/v1/ports", func(w http.ResponseWriter, r *http.Request) {
+		ports, err := lc.Ports()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, ports)
+	})
+
+	mux.HandleFunc("/v1/ports/fix", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Chains []string `json:"chains"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		changes, err := lc.FixPorts(req.Chains)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, changes)
+	})
+
+	mux.HandleFunc("/v1/mine", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Blocks int `json:"blocks"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Blocks <= 0 {
+			http.Error(w, "expected {\"blocks\": n} with n > 0", http.StatusBadRequest)
+			return
+		}
+		if err := lc.Mine(req.Blocks); err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, struct{}{})
+	})
+
+	mux.HandleFunc("/v1/automine", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Enabled bool `json:"enabled"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := lc.SetAutomine(req.Enabled); err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, struct{}{})
+	})
+
+	mux.HandleFunc("/v1/reset", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := lc.Reset(); err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, struct{}{})
+	})
+
+	return mux
+}
+
+// ControlClient talks to a launcher daemon over its unix socket.
+type ControlClient struct {
+	http *http.Client
+}
+
+func NewControlClient(sock string) *ControlClient {
+	return &ControlClient{
+		http: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", sock)
+				},
+			},
+		},
+	}
+}
+
+// ControlSocketAlive reports whether a daemon answers on sock.
+func ControlSocketAlive(sock string) bool {
+	conn, err := net.DialTimeout("unix", sock, time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// ConnectDaemon returns a client for the running daemon of node n,
+// starting one in the background first if none is listening.
+func ConnectDaemon(n int) (*ControlClient, error) {
+	sock, err := nodeSocketPath(n)
+	if err != nil {
+		return nil, err
+	}
+	if ControlSocketAlive(sock) {
+		return NewControlClient(sock), nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(sock), 0o755); err != nil {
+		return nil, err
+	}
+	logFile, err := os.OpenFile(filepath.Join(filepath.Dir(sock), daemonLogName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(exe, "--profile", profile, "--node", strconv.Itoa(n), "daemon")
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	// New session so the daemon survives the UI and its terminal
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	go cmd.Wait()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if ControlSocketAlive(sock) {
+			return NewControlClient(sock), nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return nil, fmt.Errorf("launcher daemon did not start, see %s", logFile.Name())
+}
+
+func (c *ControlClient) do(method string, path string, body interface{}, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, "http://launcher"+path, reqBody)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decodeControlResponse(resp, out)
+}
+
+func decodeControlResponse(resp *http.Response, out interface{}) error {
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		var ce controlError
+		if json.Unmarshal(b, &ce) == nil && ce.Error != "" {
+			if ce.Launch != nil {
+				return ce.Launch
+			}
+			return errors.New(ce.Error)
+		}
+		return fmt.Errorf("launcher daemon: %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *ControlClient) Providers() (map[string]ChainProvider, error) {
+	var providers map[string]ChainProvider
+	err := c.do(http.MethodGet, "/v1/providers", nil, &providers)
+	return providers, err
+}
+
+func (c *ControlClient) Status() ([]ChainStatus, error) {
+	var statuses []ChainStatus
+	err := c.do(http.MethodGet, "/v1/status", nil, &statuses)
+	return statuses, err
+}
+
+func (c *ControlClient) Start(id string) error {
+	return c.do(http.MethodPost, "/v1/chains/"+id+"/start", nil, nil)
+}
+
+func (c *ControlClient) Stop(id string, progress StopProgress) error {
+	resp, err := c.http.Post("http://launcher/v1/chains/"+id+"/stop", "application/json", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return decodeControlResponse(resp, nil)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var ev stopEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			return err
+		}
+		if ev.Done {
+			if ev.Error != "" {
+				return errors.New(ev.Error)
+			}
+			return nil
+		}
+		if progress != nil {
+			progress(ev.Step)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("launcher daemon closed the connection while stopping " + id)
+}
+
+func (c *ControlClient) Update(id string) error {
+	return c.do(http.MethodPost, "/v1/chains/"+id+"/update", nil, nil)
+}
+
+func (c *ControlClient) UseVersion(id string, version string) error {
+	return c.do(http.MethodPost, "/v1/chains/"+id+"/use", map[string]string{"version": version}, nil)
+}
+
+func (c *ControlClient) Verify(id string) (VerifyReport, error) {
+	var report VerifyReport
+	err := c.do(http.MethodGet, "/v1/chains/"+id+"/verify", nil, &report)
+	return report, err
+}
+
+func (c *ControlClient) Settings(id string) (ChainSettings, error) {
+	var s ChainSettings
+	err := c.do(http.MethodGet, "/v1/chains/"+id+"/settings", nil, &s)
+	return s, err
+}
+
+func (c *ControlClient) SaveSettings(id string, s ChainSettings) error {
+	return c.do(http.MethodPost, "/v1/chains/"+id+"/settings", s, nil)
+}
+
+func (c *ControlClient) Network() (Network, error) {
+	var resp struct {
+		Network Network `json:"network"`
+	}
+	err := c.do(http.MethodGet, "/v1/network", nil, &resp)
+	return resp.Network, err
+}
+
+func (c *ControlClient) SetNetwork(n Network) error {
+	return c.do(http.MethodPost, "/v1/network", map[string]Network{"network": n}, nil)
+}
+
+func (c *ControlClient) Ports() ([]PortCheck, error) {
+	var ports []PortCheck
+	err := c.do(http.MethodGet, "/v1/ports", nil, &ports)
+	return ports, err
+}
+
+func (c *ControlClient) FixPorts(ids []string) ([]PortChange, error) {
+	var changes []PortChange
+	err := c.do(http.MethodPost, "/v1/ports/fix", map[string][]string{"chains": ids}, &changes)
+	return changes, err
+}
+
+func (c *ControlClient) Mine(blocks int) error {
+	return c.do(http.MethodPost, "/v1/mine", map[string]int{"blocks": blocks}, nil)
+}
+
+func (c *ControlClient) SetAutomine(enabled bool) error {
+	return c.do(http.MethodPost, "/v1/automine", map[string]bool{"enabled": enabled}, nil)
+}
+
+func (c *ControlClient) Reset() error {
+	return c.do(http.MethodPost, "/v1/reset", nil, nil)
+}
+
+func (c *ControlClient) ResetChain(id string, opts ResetOptions) (ResetReport, error) {
+	var report ResetReport
+	err := c.do(http.MethodPost, "/v1/chains/"+id+"/reset", opts, &report)
+	return report, err
+}
+
+func (c *ControlClient) BackupWallet(id string, opts BackupOptions) (WalletBackup, error) {
+	var backup WalletBackup
+	err := c.do(http.MethodPost, "/v1/chains/"+id+"/backup", opts, &backup)
+	return backup, err
+}
+
+func (c *ControlClient) WalletBackups(id string) ([]WalletBackup, error) {
+	var backups []WalletBackup
+	err := c.do(http.MethodGet, "/v1/chains/"+id+"/backups", nil, &backups)
+	return backups, err
+}
+
+func (c *ControlClient) RestoreWallet(id string, opts RestoreOptions) (WalletBackup, error) {
+	var replaced WalletBackup
+	err := c.do(http.MethodPost, "/v1/chains/"+id+"/restore", opts, &replaced)
+	return replaced, err
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestControlHandlerRejectsMethods(t *testing.T) {
+	h := newControlHandler(&AppState{})
+	for _, tt := range []struct {
+		method, path string
+	}{
+		{http.MethodGet, "/v1/mine"},
+		{http.MethodPut, "/v1/mine"},
+		{http.MethodGet, "/v1/automine"},
+		{http.MethodDelete, "/v1/automine"},
+		{http.MethodPut, "/v1/network"},
+		{http.MethodDelete, "/v1/network"},
+		{http.MethodGet, "/v1/chains/testchain/start"},
+		{http.MethodPost, "/v1/chains/testchain/verify"},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, rec.Code, http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func TestControlSocketIsPrivate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".dclauncher")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sock := filepath.Join(dir, controlSocketName)
+	l, err := listenControlSocket(sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for path, want := range map[string]os.FileMode{dir: 0o700, sock: 0o600} {
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := fi.Mode().Perm(); perm != want {
+			t.Errorf("%s has mode %o, want %o", path, perm, want)
+		}
+	}
+}
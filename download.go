@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"dc-launcher/archive"
+)
+
+const artifactCacheDir = "cache"
+
+// Artifact is a downloadable build of a chain for one platform.
+type Artifact struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size,omitempty"`
+	// Empty for a plain binary installed as binName, else the archive format
+	// ("zip", "tar.gz" or "tar.xz") of a package extracted into the version's
+	// directory
+	Format string `json:"format,omitempty"`
+	// Leading path elements dropped from archive entries, e.g. 1 for a
+	// package with everything in a bitcoin-25.0 directory
+	Strip int `json:"strip,omitempty"`
+	// Base64 ed25519 signature over the raw sha256 hash, required if the
+	// chain has a signingKey
+	Signature string `json:"signature,omitempty"`
+}
+
+// DownloadProgress of an artifact, Total is 0 if the size is not known yet.
+type DownloadProgress struct {
+	Done  int64 `json:"done"`
+	Total int64 `json:"total"`
+}
+
+// Fraction returns the progress between 0 and 1.
+func (p DownloadProgress) Fraction() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return float64(p.Done) / float64(p.Total)
+}
+
+// platformKey selects the artifact for the running platform, e.g.
+// linux-amd64.
+func platformKey() string {
+	return runtime.GOOS + "-" + runtime.GOARCH
+}
+
+// artifactCachePath is where a verified artifact is kept, by hash so every
+// version only ever has to be downloaded once. The cache is shared by all
+// profiles.
+func artifactCachePath(a Artifact) (string, error) {
+	dir, err := sharedLauncherDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, artifactCacheDir, strings.ToLower(a.SHA256)), nil
+}
+
+// DownloadArtifact downloads a into the cache and returns its path. A
+// partial download from an earlier attempt is resumed. progress, if not
+// nil, is called as data comes in.
+func DownloadArtifact(ctx context.Context, a Artifact, progress func(DownloadProgress)) (string, error) {
+	if a.URL == "" || len(a.SHA256) != sha256.Size*2 {
+		return "", fmt.Errorf("artifact needs a url and a sha256 hash")
+	}
+	path, err := artifactCachePath(a)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+
+	part := path + ".part"
+	f, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.URL, nil)
+	if err != nil {
+		return "", err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		println(fmt.Sprintf("Resuming download of %s at %d bytes", a.URL, offset))
+	case http.StatusOK:
+		// No range support, start over
+		offset = 0
+		if err := f.Truncate(0); err != nil {
+			return "", err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Already complete, verify below
+	default:
+		return "", fmt.Errorf("GET %s: %s", a.URL, resp.Status)
+	}
+
+	p := DownloadProgress{Done: offset, Total: a.Size}
+	if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable && resp.ContentLength > 0 {
+		p.Total = offset + resp.ContentLength
+	}
+	if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		w := &progressWriter{w: f, p: p, report: progress}
+		if _, err := io.Copy(w, resp.Body); err != nil {
+			return "", fmt.Errorf("downloading %s: %w", a.URL, err)
+		}
+		w.flush()
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	sum, err := fileSHA256(part)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(sum, a.SHA256) {
+		// Resuming a corrupt download would never succeed
+		os.Remove(part)
+		return "", fmt.Errorf("%s: sha256 is %s, expected %s", a.URL, sum, a.SHA256)
+	}
+	return path, os.Rename(part, path)
+}
+
+// progressWriter reports progress at most a few times a second.
+type progressWriter struct {
+	w      io.Writer
+	p      DownloadProgress
+	report func(DownloadProgress)
+	last   time.Time
+}
+
+func (pw *progressWriter) Write(b []byte) (int, error) {
+	n, err := pw.w.Write(b)
+	pw.p.Done += int64(n)
+	if time.Since(pw.last) > 250*time.Millisecond {
+		pw.flush()
+	}
+	return n, err
+}
+
+func (pw *progressWriter) flush() {
+	pw.last = time.Now()
+	if pw.report != nil {
+		pw.report(pw.p)
+	}
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// installArtifact installs the downloaded artifact at path into dir.
+func installArtifact(cd *ChainData, a Artifact, path string, dir string) error {
+	if a.Format == "" {
+		binDir := cd.Driver().BinDir(dir)
+		if err := os.MkdirAll(binDir, 0o755); err != nil {
+			return err
+		}
+		return copyExecutable(path, filepath.Join(binDir, cd.BinName))
+	}
+	return archive.Extract(path, dir, archive.Format(a.Format), a.Strip)
+}
+
+// copyExecutable installs src at dst. The new binary is written next to dst
+// and renamed over it, so a running old version is not disturbed.
+func copyExecutable(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp := dst + ".new"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"dc-launcher/conf"
+)
+
+// ChainKind selects the ChainDriver used for a chain, see the kind field in
+// chains.json.
+type ChainKind string
+
+const (
+	KindDrivechain       ChainKind = "drivechain"        // BIP 300/301 mainchain
+	KindBitcoinSidechain ChainKind = "bitcoin-sidechain" // Bitcoin Core based sidechains, e.g. testchain
+	KindLatestCore       ChainKind = "latestcore"        // Recent Bitcoin Core on regtest
+	KindThunder          ChainKind = "thunder"           // Configured with flags, no RPC yet
+	KindBitnames         ChainKind = "bitnames"          // Shipped as an archive with a start script
+)
+
+// errNoGracefulStop is returned by ChainDriver.Stop if the chain can only be
+// stopped with signals.
+var errNoGracefulStop = errors.New("chain has no graceful stop")
+
+// ChainDriver holds everything that differs between kinds of chains.
+type ChainDriver interface {
+	// BinDir is the directory of the chain binary in an installed version
+	BinDir(installDir string) string
+	// BuildArgs returns the program that starts the chain and its arguments
+	BuildArgs(cd *ChainData, as *AppState) (string, []string)
+	// WriteDefaultConf writes the conf file of a fresh install
+	WriteDefaultConf(cp ChainProvider, cd *ChainData) error
+	// PostStart runs after the chain process was started
+	PostStart(cd *ChainData, cs *ChainState, as *AppState)
+	// HealthCheck refreshes cs from the running chain and reports whether
+	// anything changed
+	HealthCheck(cd *ChainData, cs *ChainState) bool
+	SupportsRPC() bool
+	// WalletFiles returns the directory holding the wallet and the names
+	// of its files and directories in there
+	WalletFiles(cd *ChainData) (string, []string)
+	// ChainDataPaths returns the blocks, state and indexes of the chain,
+	// without the wallet, conf or installed binaries
+	ChainDataPaths(cd *ChainData) ([]string, error)
+	// Stop asks the chain to shut down, without waiting for it
+	Stop(cd *ChainData) error
+}
+
+var chainDrivers = map[ChainKind]ChainDriver{
+	KindDrivechain:       drivechainDriver{},
+	KindBitcoinSidechain: bitcoinSidechainDriver{},
+	KindLatestCore:       latestCoreDriver{},
+	KindThunder:          thunderDriver{},
+	KindBitnames:         bitnamesDriver{},
+}
+
+// DriverFor returns the driver for the provider's kind. Providers without a
+// kind, e.g. from a chains.json written by an older launcher, are matched by
+// their id.
+func DriverFor(cp ChainProvider) (ChainKind, ChainDriver, error) {
+	kind := cp.Kind
+	if kind == "" {
+		kind = ChainKind(cp.ID)
+		if _, ok := chainDrivers[kind]; !ok {
+			kind = KindBitcoinSidechain
+		}
+	}
+	drv, ok := chainDrivers[kind]
+	if !ok {
+		return kind, nil, fmt.Errorf("%s: unknown chain kind %q", cp.ID, kind)
+	}
+	return kind, drv, nil
+}
+
+// Driver returns the driver of the chain's kind.
+func (cd *ChainData) Driver() ChainDriver {
+	if drv, ok := chainDrivers[cd.Kind]; ok {
+		return drv
+	}
+	return bitcoinSidechainDriver{}
+}
+
+// bitcoinDriver is shared by the chains based on Bitcoin Core.
+type bitcoinDriver struct{}
+
+func (bitcoinDriver) BinDir(installDir string) string {
+	return installDir
+}
+
+func (bitcoinDriver) BuildArgs(cd *ChainData, as *AppState) (string, []string) {
+	return filepath.Join(cd.BinDir, cd.BinName), []string{"-conf=" + filepath.Join(cd.ConfDir, cd.ConfName)}
+}
+
+func (bitcoinDriver) PostStart(cd *ChainData, cs *ChainState, as *AppState) {}
+
+func (bitcoinDriver) HealthCheck(cd *ChainData, cs *ChainState) bool {
+	return PollChainState(cd, cs)
+}
+
+func (bitcoinDriver) SupportsRPC() bool {
+	return true
+}
+
+// bitcoinWalletFiles are wallet.dat of the older Core based chains, with
+// database and db.log of its BDB environment, and the wallets directory of
+// newer ones. All of them are in the network directory.
+var bitcoinWalletFiles = []string{"wallet.dat", "wallets", "database", "db.log"}
+
+func (bitcoinDriver) WalletFiles(cd *ChainData) (string, []string) {
+	return cd.NetDir, bitcoinWalletFiles
+}
+
+func (bitcoinDriver) ChainDataPaths(cd *ChainData) ([]string, error) {
+	return pathsExcept(cd.NetDir, append(launcherFiles(cd), bitcoinWalletFiles...))
+}
+
+func (bitcoinDriver) Stop(cd *ChainData) error {
+	ctx, cancel := rpcContext()
+	defer cancel()
+	_, err := cd.RPCClient().Do(ctx, "stop")
+	return err
+}
+
+func writeConf(cd *ChainData, f *conf.File) error {
+	path := filepath.Join(cd.ConfDir, cd.ConfName)
+	println("Writing " + path)
+	return f.WriteFile(path, 0o600)
+}
+
+type setting struct {
+	section, key, value string
+}
+
+func setAll(f *conf.File, settings []setting) error {
+	for _, s := range settings {
+		if err := f.Set(s.section, s.key, s.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultConf returns the conf every drivechain based chain starts with.
+func defaultConf() *conf.File {
+	f, err := conf.Parse(chainConfBytes)
+	if err != nil {
+		panic("chain.conf: " + err.Error())
+	}
+	return f
+}
+
+type drivechainDriver struct{ bitcoinDriver }
+
+func (drivechainDriver) WriteDefaultConf(cp ChainProvider, cd *ChainData) error {
+	f := defaultConf()
+	err := setAll(f, []setting{
+		{"", "datadir", cd.ConfDir},
+		{"regtest", "rpcport", strconv.Itoa(cp.DefaultPort)},
+	})
+	if err != nil {
+		return err
+	}
+	return writeConf(cd, f)
+}
+
+type bitcoinSidechainDriver struct{ bitcoinDriver }
+
+// BuildArgs points the sidechain at the drivechain of its profile and node,
+// whose RPC port moves with them and the network.
+func (d bitcoinSidechainDriver) BuildArgs(cd *ChainData, as *AppState) (string, []string) {
+	program, args := d.bitcoinDriver.BuildArgs(cd, as)
+	dcd := as.drivechainData()
+	return program, append(args, "-mainchainrpcport="+strconv.Itoa(dcd.Port))
+}
+
+func (bitcoinSidechainDriver) WriteDefaultConf(cp ChainProvider, cd *ChainData) error {
+	f := defaultConf()
+	err := setAll(f, []setting{
+		{"", "datadir", cd.ConfDir},
+		{"regtest", "rpcport", strconv.Itoa(cp.DefaultPort)},
+		{"", "slot", strconv.Itoa(cp.DefaultSlot)},
+	})
+	if err != nil {
+		return err
+	}
+	return writeConf(cd, f)
+}
+
+type latestCoreDriver struct{ bitcoinDriver }
+
+func (latestCoreDriver) WriteDefaultConf(cp ChainProvider, cd *ChainData) error {
+	f := &conf.File{}
+	err := setAll(f, []setting{
+		{"", "chain", "regtest"},
+		{"", "server", "1"},
+		{"", "splash", "0"},
+		{"", "slot", strconv.Itoa(cp.DefaultSlot)},
+		{"", "datadir", cd.ConfDir},
+		// No rpcuser or rpcpassword, Core writes a cookie the launcher reads
+		{"regtest", "rpcport", strconv.Itoa(cp.DefaultPort)},
+	})
+	if err != nil {
+		return err
+	}
+	return writeConf(cd, f)
+}
+
+// PostStart creates the wallet on first launch, newer Core versions don't
+// create one by default.
+func (latestCoreDriver) PostStart(cd *ChainData, cs *ChainState, as *AppState) {
+	d := filepath.Join(cd.NetDir, "wallets")
+	empty, err := IsDirEmpty(d)
+	if empty || err != nil {
+		time.AfterFunc(time.Duration(1)*time.Second, func() {
+			if err := LatestCoreCreateWallet(as, cd, cs); err != nil {
+				println(err.Error())
+			}
+		})
+	}
+}
+
+type thunderDriver struct{}
+
+func (thunderDriver) BinDir(installDir string) string {
+	return installDir
+}
+
+func (thunderDriver) BuildArgs(cd *ChainData, as *AppState) (string, []string) {
+	netAddr := fmt.Sprintf("127.0.0.1:%v", cd.Port)
+	dcd := as.drivechainData()
+	dcAddr := fmt.Sprintf("127.0.0.1:%v", dcd.Port)
+	user, pass, err := dcd.Credentials()
+	if err != nil {
+		println(err.Error())
+	}
+	return filepath.Join(cd.BinDir, cd.BinName), []string{"-d", cd.ConfDir, "-n", netAddr, "-m", dcAddr, "-u", user, "-p", pass}
+}
+
+func (thunderDriver) WriteDefaultConf(cp ChainProvider, cd *ChainData) error {
+	f := &conf.File{}
+	err := setAll(f, []setting{
+		{"", "rpcport", strconv.Itoa(cp.DefaultPort)},
+		{"", "slot", strconv.Itoa(cp.DefaultSlot)},
+	})
+	if err != nil {
+		return err
+	}
+	return writeConf(cd, f)
+}
+
+func (thunderDriver) PostStart(cd *ChainData, cs *ChainState, as *AppState) {}
+
+// TODO: Thunder needs rpc
+func (thunderDriver) HealthCheck(cd *ChainData, cs *ChainState) bool {
+	return false
+}
+
+func (thunderDriver) SupportsRPC() bool {
+	return false
+}
+
+// thunderWalletFiles is the LMDB environment of the wallet, next to the
+// node's own in the data directory.
+var thunderWalletFiles = []string{"wallet.mdb"}
+
+func (thunderDriver) WalletFiles(cd *ChainData) (string, []string) {
+	return cd.ConfDir, thunderWalletFiles
+}
+
+func (thunderDriver) ChainDataPaths(cd *ChainData) ([]string, error) {
+	return pathsExcept(cd.ConfDir, append(launcherFiles(cd), thunderWalletFiles...))
+}
+
+func (thunderDriver) Stop(cd *ChainData) error {
+	return errNoGracefulStop
+}
+
+type bitnamesDriver struct{ bitcoinSidechainDriver }
+
+// BinDir is inside the extracted archive, the chain is started through
+// its start.sh.
+func (bitnamesDriver) BinDir(installDir string) string {
+	return filepath.Join(installDir, "usr", "bin")
+}
+
+func (bitnamesDriver) BuildArgs(cd *ChainData, as *AppState) (string, []string) {
+	return filepath.Join(cd.InstallDir, "start.sh"), nil
+}
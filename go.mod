@@ -5,6 +5,8 @@ go 1.20
 require (
 	fyne.io/fyne/v2 v2.3.6-0.20230720061213-19e0c73660eb
 	github.com/pkg/browser v0.0.0-20210911075715-681adbf594b8
+	github.com/ulikunitz/xz v0.5.12
+	golang.org/x/crypto v0.8.0
 )
 
 require (
@@ -32,7 +34,6 @@ require (
 )
 
 require (
-	github.com/biter777/processex v0.0.0-20210102170504-01bb369eda71
 	github.com/fredbi/uri v0.1.0 // indirect
 	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c // indirect
 	github.com/srwiley/rasterx v0.0.0-20220730225603-2ab79fcdd4ef // indirect
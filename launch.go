@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LaunchTemplate describes how to start a chain, see the launch section of
+// a chain in chains.json. Every field may contain placeholders like
+// {datadir} or {mainchain.rpcport}, see launchVars for the full list.
+type LaunchTemplate struct {
+	Exec    string            `json:"exec,omitempty"` // Defaults to {bindir}/{binname}
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	WorkDir string            `json:"workdir,omitempty"`
+}
+
+// LaunchCommand is a launch template with all placeholders filled in.
+type LaunchCommand struct {
+	Exec    string
+	Args    []string
+	Env     []string // KEY=value, added to the launcher's environment
+	WorkDir string
+}
+
+var placeholderRe = regexp.MustCompile(`\{[a-z.]+\}`)
+
+// launchVars returns the values of the placeholders for cd.
+func launchVars(cd *ChainData, as *AppState) map[string]string {
+	homeDir, _ := profileHome()
+	vars := map[string]string{
+		"{home}":        homeDir,
+		"{id}":          cd.ID,
+		"{datadir}":     cd.ConfDir,
+		"{bindir}":      cd.BinDir,
+		"{installdir}":  cd.InstallDir,
+		"{binname}":     cd.BinName,
+		"{conffile}":    filepath.Join(cd.ConfDir, cd.ConfName),
+		"{rpchost}":     rpcHost(cd),
+		"{rpcport}":     strconv.Itoa(cd.Port),
+		"{rpcuser}":     cd.RPCUser,
+		"{rpcpassword}": cd.RPCPass,
+		"{slot}":        strconv.Itoa(cd.Slot),
+	}
+	if as != nil {
+		dcd := as.drivechainData()
+		vars["{mainchain.datadir}"] = dcd.ConfDir
+		vars["{mainchain.rpchost}"] = rpcHost(&dcd)
+		vars["{mainchain.rpcport}"] = strconv.Itoa(dcd.Port)
+		user, pass, err := dcd.Credentials()
+		if err != nil {
+			println(err.Error())
+		}
+		vars["{mainchain.rpcuser}"] = user
+		vars["{mainchain.rpcpassword}"] = pass
+	}
+	return vars
+}
+
+func rpcHost(cd *ChainData) string {
+	if cd.RPCHost != "" {
+		return cd.RPCHost
+	}
+	return "127.0.0.1"
+}
+
+// Expand fills in the placeholders of the template for cd. Unknown
+// placeholders are an error so typos in chains.json don't silently end up
+// on the command line.
+func (lt *LaunchTemplate) Expand(cd *ChainData, as *AppState) (LaunchCommand, error) {
+	vars := launchVars(cd, as)
+	var unknown []string
+	expand := func(s string) string {
+		return placeholderRe.ReplaceAllStringFunc(s, func(p string) string {
+			v, ok := vars[p]
+			if !ok {
+				unknown = append(unknown, p)
+				return p
+			}
+			return v
+		})
+	}
+
+	program := lt.Exec
+	if program == "" {
+		program = "{bindir}/{binname}"
+	}
+	lc := LaunchCommand{
+		Exec:    expand(program),
+		WorkDir: expand(lt.WorkDir),
+	}
+	for _, arg := range lt.Args {
+		lc.Args = append(lc.Args, expand(arg))
+	}
+	for k, v := range lt.Env {
+		lc.Env = append(lc.Env, k+"="+expand(v))
+	}
+	// Map order is random, keep the environment stable between launches
+	sort.Strings(lc.Env)
+
+	if len(unknown) > 0 {
+		return lc, &LaunchError{
+			ChainID: cd.ID,
+			Kind:    LaunchFailed,
+			Fix:     fmt.Sprintf("Check the launch section of %s in chains.json", cd.ID),
+			Err:     fmt.Errorf("unknown placeholder %s", strings.Join(unknown, ", ")),
+		}
+	}
+	return lc, nil
+}
+
+// launchCommand returns the command line for cd, from its launch template
+// if chains.json has one and from its driver otherwise.
+func launchCommand(cd *ChainData, as *AppState) (LaunchCommand, error) {
+	if cd.Launch != nil {
+		lc, err := cd.Launch.Expand(cd, as)
+		lc.Args = append(lc.Args, portArgs(cd)...)
+		lc.Args = append(lc.Args, peerArgs(as, cd)...)
+		return lc, err
+	}
+	program, args := cd.Driver().BuildArgs(cd, as)
+	args = append(args, portArgs(cd)...)
+	return LaunchCommand{Exec: program, Args: append(args, peerArgs(as, cd)...)}, nil
+}
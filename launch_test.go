@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLaunchTemplateExpand(t *testing.T) {
+	cd := &ChainData{
+		ID:       "testchain",
+		ConfDir:  "/data/testchain",
+		ConfName: "testchain.conf",
+		BinDir:   "/opt/testchain/bin",
+		BinName:  "testchaind",
+		Port:     19000,
+		RPCUser:  "user",
+		RPCPass:  "pass",
+		Slot:     7,
+	}
+	as := &AppState{dcd: ChainData{ID: "drivechain", ConfDir: "/data/drivechain", Port: 18443, RPCUser: "dcuser", RPCPass: "dcpass"}}
+
+	tests := []struct {
+		name string
+		lt   LaunchTemplate
+		want LaunchCommand
+	}{
+		{
+			name: "default exec",
+			lt:   LaunchTemplate{Args: []string{"-conf={conffile}", "-slot={slot}"}},
+			want: LaunchCommand{
+				Exec: "/opt/testchain/bin/testchaind",
+				Args: []string{"-conf=/data/testchain/testchain.conf", "-slot=7"},
+			},
+		},
+		{
+			name: "mainchain placeholders",
+			lt: LaunchTemplate{
+				Exec: "{datadir}/start.sh",
+				Args: []string{"--main", "{mainchain.rpchost}:{mainchain.rpcport}", "-u", "{mainchain.rpcuser}", "-p", "{mainchain.rpcpassword}"},
+			},
+			want: LaunchCommand{
+				Exec: "/data/testchain/start.sh",
+				Args: []string{"--main", "127.0.0.1:18443", "-u", "dcuser", "-p", "dcpass"},
+			},
+		},
+		{
+			name: "env sorted and workdir",
+			lt: LaunchTemplate{
+				Env:     map[string]string{"RPC_PORT": "{rpcport}", "DATA": "{datadir}", "ID": "{id}"},
+				WorkDir: "{bindir}",
+			},
+			want: LaunchCommand{
+				Exec:    "/opt/testchain/bin/testchaind",
+				Env:     []string{"DATA=/data/testchain", "ID=testchain", "RPC_PORT=19000"},
+				WorkDir: "/opt/testchain/bin",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.lt.Expand(cd, as)
+			if err != nil {
+				t.Fatalf("Expand: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expand = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLaunchTemplateExpandUnknownPlaceholder(t *testing.T) {
+	tests := []LaunchTemplate{
+		{Args: []string{"-datadir={datadri}"}},
+		{Exec: "{bindir}/{nope}"},
+		{Env: map[string]string{"X": "{mainchain.slot}"}},
+	}
+	for _, lt := range tests {
+		_, err := lt.Expand(&ChainData{ID: "testchain"}, nil)
+		var le *LaunchError
+		if !errors.As(err, &le) || le.Kind != LaunchFailed {
+			t.Errorf("Expand(%+v) error = %v, want a LaunchError", lt, err)
+		}
+	}
+}
@@ -1,20 +1,56 @@
 package main
 
+import "os"
+
 var (
 	as  *AppState
 	mui *MainUI
 )
 
 func main() {
-	as = NewAppState("com.layertwolabs.dclauncher", "Drivechain Launcher")
+	o, args, code, exit := parseCLIOptions(os.Args[1:])
+	if exit {
+		os.Exit(code)
+	}
+	// A command runs the launcher headless, see cli.go
+	if len(args) > 0 || o.set() {
+		os.Exit(RunCLI(o, args))
+	}
+
+	as = NewAppState()
+	as.InitUI("com.layertwolabs.dclauncher", profileTitle("Drivechain Launcher"))
+
+	// Without --profile the user picks one if there is more than the
+	// default
+	if profiles, err := ListProfiles(); o.profile == "" && err == nil && len(profiles) > 1 {
+		ShowProfileChooser(as, profiles, startUI)
+	} else {
+		startUI()
+	}
+
+	as.w.ShowAndRun()
+}
 
-	err := ConfInit(as)
+func startUI() {
+	// Chains are owned by the launcher daemon so they keep running when the
+	// window is closed
+	var ctl Controller
+	dc, err := ConnectDaemon(node)
+	if err == nil {
+		as.cp, err = dc.Providers()
+		ctl = dc
+	}
 	if err != nil {
-		println(err.Error())
+		println("Running without launcher daemon: " + err.Error())
+		err := ConfInit(as)
+		if err != nil {
+			println(err.Error())
+		}
+		AdoptRunningChains(as, true)
+		ctl = NewLocalController(as, false)
 	}
 
-	mui = NewMainUI(as)
+	mui = NewMainUI(as, ctl)
+	mui.ShowNodes()
 	mui.Refresh()
-
-	mui.as.w.ShowAndRun()
 }
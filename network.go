@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Network is the network every chain of the launcher runs on, set with
+// "network" in launcher.json.
+type Network string
+
+const (
+	Regtest Network = "regtest"
+	Signet  Network = "signet"
+	Testnet Network = "testnet" // The public drivechain testnet
+)
+
+var networks = []Network{Regtest, Signet, Testnet}
+
+// ParseNetwork returns the network named s, regtest if s is empty.
+func ParseNetwork(s string) (Network, error) {
+	if s == "" {
+		return Regtest, nil
+	}
+	for _, n := range networks {
+		if string(n) == s {
+			return n, nil
+		}
+	}
+	return "", fmt.Errorf("unknown network %q, expected regtest, signet or testnet", s)
+}
+
+// Section returns the name bitcoind uses for the network in conf sections,
+// as in ChainData.Network.
+func (n Network) Section() string {
+	if n == Testnet {
+		return "test"
+	}
+	return string(n)
+}
+
+// flag returns the conf flag selecting the network.
+func (n Network) flag() string {
+	return string(n)
+}
+
+// Port moves a catalogue default port, which is meant for regtest, so the
+// chains of different networks never share ports.
+func (n Network) Port(port int) int {
+	switch n {
+	case Testnet:
+		return port + 1000
+	case Signet:
+		return port + 2000
+	}
+	return port
+}
+
+// networkOf returns the launcher network of a bitcoind network section.
+func networkOf(section string) (Network, bool) {
+	for _, n := range networks {
+		if n.Section() == section {
+			return n, true
+		}
+	}
+	return "", false
+}
+
+// usesNetworkConf tells whether the chain selects its network in its conf
+// file. Thunder is configured on the command line.
+func usesNetworkConf(cd *ChainData) bool {
+	switch cd.Kind {
+	case KindDrivechain, KindBitcoinSidechain, KindLatestCore, KindBitnames:
+		return true
+	}
+	return false
+}
+
+// canGenerate tells whether blocks can be mined on demand, only regtest
+// has generate.
+func canGenerate(cd *ChainData) bool {
+	return cd.Network == Regtest.Section()
+}
+
+var errNotRegtest = errors.New("blocks can only be generated on regtest")
+
+// setChainNetwork rewrites the conf of cd for net. Chains using chain= get
+// it changed and the settings of the old network's section move to the new
+// one, others get their network flag replaced. The RPC port moves with the
+// network unless it was changed from the default.
+func setChainNetwork(cd *ChainData, cp ChainProvider, net Network) error {
+	c, err := chainConf(cd)
+	if err != nil {
+		return err
+	}
+	f := c.Main()
+	old := c.Network()
+	if old == net.Section() {
+		return nil
+	}
+
+	if _, ok := f.Get("", "chain"); ok {
+		if err := f.Set("", "chain", net.Section()); err != nil {
+			return err
+		}
+	} else {
+		for _, n := range networks {
+			f.Unset("", n.flag())
+		}
+		if err := f.Set("", net.flag(), "1"); err != nil {
+			return err
+		}
+	}
+	if old != "main" {
+		for _, key := range f.Keys(old) {
+			values := f.GetAll(old, key)
+			f.Unset(old, key)
+			f.Unset(net.Section(), key)
+			for _, v := range values {
+				if err := f.Add(net.Section(), key, v); err != nil {
+					return err
+				}
+			}
+		}
+		f.RemoveSection(old)
+	}
+
+	if oldNet, ok := networkOf(old); ok {
+		section := ""
+		if _, ok := f.Get(net.Section(), "rpcport"); ok {
+			section = net.Section()
+		}
+		if v, ok := f.Get(section, "rpcport"); ok && v == strconv.Itoa(oldNet.Port(cp.DefaultPort)) {
+			if err := f.Set(section, "rpcport", strconv.Itoa(net.Port(cp.DefaultPort))); err != nil {
+				return err
+			}
+		}
+	}
+	println(fmt.Sprintf("Switching %s from %s to %s", cd.ID, old, net.Section()))
+	return f.WriteFile(c.Path, 0o600)
+}
+
+// switchNetwork moves cd to the launcher network if it isn't running.
+// Default confs are written for regtest and switched from there.
+func (as *AppState) switchNetwork(cd *ChainData) error {
+	if !usesNetworkConf(cd) || cd.Network == as.network.Section() {
+		return nil
+	}
+	if as.sup.IsRunning(cd.ID) {
+		return nil
+	}
+	if _, ok := runningPID(cd); ok {
+		return nil
+	}
+	if err := setChainNetwork(cd, as.cp[cd.ID], as.network); err != nil {
+		return err
+	}
+	return as.loadChainConf(cd)
+}
+
+// SetNetwork makes n the launcher network. Stopped chains switch right away,
+// running ones the next time they are started.
+func SetNetwork(as *AppState, n Network) error {
+	s, err := LoadSettings()
+	if err != nil {
+		return err
+	}
+	s.Network = string(n)
+	if n == Regtest {
+		s.Network = ""
+	}
+	if err := SaveLauncherSettings(s); err != nil {
+		return err
+	}
+	as.network = n
+	var errs []error
+	for _, id := range chainIDs(as) {
+		cd, _, err := chainByID(as, id)
+		if err != nil {
+			continue
+		}
+		if err := as.switchNetwork(cd); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", id, err))
+			continue
+		}
+		as.setChainData(cd)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSignetSidechainsUseTheirDrivechain(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir, err := launcherDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSettings(dir, LauncherSettings{Network: string(Signet)}); err != nil {
+		t.Fatal(err)
+	}
+	checkMainchainPort(t, 18443+2000)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// Nodes are copies of the chains of a profile, each with its own data
+// directories, ports and launcher daemon, e.g. to test reorgs between two
+// drivechain nodes. Node 1 is the profile itself, node n lives in
+// nodes/<n> of its launcher directory with the same layout. Bitcoin Core
+// based chains are connected to their copies in the other nodes with
+// addnode.
+const nodesDir = "nodes"
+
+// node is the node of this process, 1 unless chosen with --node.
+var node = 1
+
+// nodeRoot returns the root of node n of profile name.
+func nodeRoot(name string, n int) (string, error) {
+	root, err := profileRoot(name)
+	if err != nil || n == 1 {
+		return root, err
+	}
+	return filepath.Join(root, ".dclauncher", nodesDir, strconv.Itoa(n)), nil
+}
+
+// ListNodes returns the nodes of profile name in order, 1 first.
+func ListNodes(name string) ([]int, error) {
+	root, err := profileRoot(name)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(root, ".dclauncher", nodesDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	nodes := []int{1}
+	for _, e := range entries {
+		if n, err := strconv.Atoi(e.Name()); err == nil && n > 1 && e.IsDir() {
+			nodes = append(nodes, n)
+		}
+	}
+	sort.Ints(nodes)
+	return nodes, nil
+}
+
+// SetNode makes n the node of this process. Nodes other than 1 must have
+// been created.
+func SetNode(n int) error {
+	if n < 1 {
+		return fmt.Errorf("invalid node %d", n)
+	}
+	root, err := nodeRoot(profile, n)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return fmt.Errorf("no node %d, create it with: dc-launcher nodes %d", n, n)
+	}
+	node = n
+	return nil
+}
+
+// CreateNodes creates the missing nodes of the profile up to n and returns
+// them. New nodes get free ports and the network of node 1.
+func CreateNodes(n int) ([]int, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("invalid number of nodes %d", n)
+	}
+	first, err := nodeRoot(profile, 1)
+	if err != nil {
+		return nil, err
+	}
+	base, err := readSettings(filepath.Join(first, ".dclauncher"))
+	if err != nil {
+		return nil, err
+	}
+
+	var created []int
+	for i := 2; i <= n; i++ {
+		root, err := nodeRoot(profile, i)
+		if err != nil {
+			return created, err
+		}
+		if _, err := os.Stat(root); err == nil {
+			continue
+		}
+		s := LauncherSettings{Network: base.Network}
+		if s.PortOffset, err = freePortOffset(); err != nil {
+			return created, err
+		}
+		dir := filepath.Join(root, ".dclauncher")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return created, err
+		}
+		if err := writeSettings(dir, s); err != nil {
+			return created, err
+		}
+		println(fmt.Sprintf("Created node %d in %s, ports moved by %d", i, root, s.PortOffset))
+		created = append(created, i)
+	}
+	return created, nil
+}
+
+// peerArgs connects drivechain, latestcore and bitcoin sidechains to their
+// copies in the other nodes. Bitcoin sidechains whose P2P port the launcher
+// doesn't know aren't listening if the ports of the profile or node were
+// moved, their default would clash with another copy.
+func peerArgs(as *AppState, cd *ChainData) []string {
+	switch cd.Kind {
+	case KindDrivechain, KindLatestCore:
+	case KindBitcoinSidechain:
+		if cd.P2PPort == 0 {
+			if as.portOffset != 0 {
+				return []string{"-listen=0"}
+			}
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	nodes, err := ListNodes(profile)
+	if err != nil {
+		println(err.Error())
+		return nil
+	}
+	var args []string
+	for _, n := range nodes {
+		if n == node {
+			continue
+		}
+		port, err := nodeP2PPort(n, as.cp[cd.ID], cd)
+		if err != nil {
+			continue
+		}
+		args = append(args, "-addnode=127.0.0.1:"+strconv.Itoa(port))
+	}
+	return args
+}
+
+var errNoNodeConf = errors.New("chain was never set up in this node")
+
+// nodeP2PPort returns the P2P port of the copy of cd in node n, from its
+// conf or the default moved by the node's port offset.
+func nodeP2PPort(n int, cp ChainProvider, cd *ChainData) (int, error) {
+	root, err := nodeRoot(profile, n)
+	if err != nil {
+		return 0, err
+	}
+	s, err := readSettings(filepath.Join(root, ".dclauncher"))
+	if err != nil {
+		return 0, err
+	}
+	peer := ChainData{
+		ID:       cd.ID,
+		Kind:     cd.Kind,
+		ConfName: cd.ConfName,
+		ConfDir:  filepath.Join(root, cp.DefaultDir),
+	}
+	if _, err := os.Stat(filepath.Join(peer.ConfDir, peer.ConfName)); err != nil {
+		return 0, errNoNodeConf
+	}
+	if err := loadConf(&peer); err != nil {
+		return 0, err
+	}
+	if peer.P2PPort != 0 {
+		return peer.P2PPort, nil
+	}
+	port, _, ok := defaultP2PPort(&peer, cp, s.PortOffset)
+	if !ok {
+		return 0, fmt.Errorf("no P2P port for %s in node %d", cd.ID, n)
+	}
+	return port, nil
+}
+
+// nodeSocketPath is the control socket of the daemon of node n.
+func nodeSocketPath(n int) (string, error) {
+	root, err := nodeRoot(profile, n)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, ".dclauncher", controlSocketName), nil
+}
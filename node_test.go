@@ -0,0 +1,127 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestPeerArgs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, ".dclauncher", nodesDir, "2", ".dclauncher")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSettings(dir, LauncherSettings{PortOffset: 20}); err != nil {
+		t.Fatal(err)
+	}
+	// Set up in node 2, thunder never was
+	for _, c := range []struct{ dir, name string }{{".drivechain", "drivechain.conf"}, {".testchain", "testchain.conf"}} {
+		confDir := filepath.Join(home, ".dclauncher", nodesDir, "2", c.dir)
+		if err := os.MkdirAll(confDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(confDir, c.name), []byte("regtest=1\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	providers := map[string]ChainProvider{
+		"drivechain": {ID: "drivechain", DefaultDir: ".drivechain"},
+		"testchain":  {ID: "testchain", DefaultDir: ".testchain", DefaultP2PPort: 19100},
+		"thunder":    {ID: "thunder", DefaultDir: ".thunder"},
+	}
+	for _, tt := range []struct {
+		name   string
+		cd     ChainData
+		offset int
+		want   []string
+	}{
+		{"drivechain", ChainData{ID: "drivechain", Kind: KindDrivechain, ConfName: "drivechain.conf", P2PPort: 18444}, 0, []string{"-addnode=127.0.0.1:18464"}},
+		{"sidechain", ChainData{ID: "testchain", Kind: KindBitcoinSidechain, ConfName: "testchain.conf", P2PPort: 19100}, 0, []string{"-addnode=127.0.0.1:19120"}},
+		{"sidechain without P2P port", ChainData{ID: "testchain", Kind: KindBitcoinSidechain, ConfName: "testchain.conf"}, 0, nil},
+		{"sidechain without P2P port, moved", ChainData{ID: "testchain", Kind: KindBitcoinSidechain, ConfName: "testchain.conf"}, 10, []string{"-listen=0"}},
+		{"not in the other node", ChainData{ID: "thunder", Kind: KindBitcoinSidechain, ConfName: "thunder.conf", P2PPort: 19200}, 0, nil},
+		{"thunder", ChainData{ID: "thunder", Kind: KindThunder, ConfName: "thunder.conf"}, 0, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			as := &AppState{cp: providers, portOffset: tt.offset}
+			if got := peerArgs(as, &tt.cd); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("peerArgs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// checkMainchainPort sets the launcher up like at start and checks the
+// sidechains are started against drivechain's RPC port, which must be port.
+func checkMainchainPort(t *testing.T, port int) {
+	t.Helper()
+	as := NewAppState()
+	if err := ConfInit(as); err != nil {
+		t.Fatal(err)
+	}
+	if dcd := as.drivechainData(); dcd.Port != port {
+		t.Fatalf("drivechain RPC port = %d, want %d", dcd.Port, port)
+	}
+	for id, want := range map[string]string{
+		"testchain": "-mainchainrpcport=" + strconv.Itoa(port),
+		"bitassets": "-mainchainrpcport=" + strconv.Itoa(port),
+		"thunder":   "127.0.0.1:" + strconv.Itoa(port),
+	} {
+		cd, _, err := chainByID(as, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, lc := range []*LaunchTemplate{cd.Launch, nil} {
+			cd.Launch = lc
+			cmd, err := launchCommand(cd, as)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := false
+			for _, arg := range cmd.Args {
+				found = found || arg == want
+			}
+			if !found {
+				t.Errorf("%s started with %q, want %s", id, cmd.Args, want)
+			}
+		}
+	}
+
+	// Bitnames is started by a script and reads the port from its conf
+	cd, _, err := chainByID(as, "bitnames")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pointAtMainchain(cd, port); err != nil {
+		t.Fatal(err)
+	}
+	c, err := chainConf(cd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _, _ := c.GetInt("mainchainrpcport"); v != port {
+		t.Errorf("bitnames mainchainrpcport = %d, want %d", v, port)
+	}
+}
+
+func TestNodeSidechainsUseTheirDrivechain(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, ".dclauncher", nodesDir, "2", ".dclauncher")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSettings(dir, LauncherSettings{PortOffset: 20}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetNode(2); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { node = 1 })
+	checkMainchainPort(t, 18443+20)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// How often the rows of another node are updated, and the longest wait
+// between tries while its daemon doesn't answer.
+const (
+	nodePollInterval   = time.Second
+	nodePollMaxBackoff = 30 * time.Second
+)
+
+// nodeGroup shows the chains of another node of the profile as compact
+// rows, talking to the launcher daemon of that node.
+type nodeGroup struct {
+	mui  *MainUI
+	n    int
+	box  *fyne.Container
+	quit chan struct{} // Closed with the window
+
+	mu   sync.Mutex // Guards ctl and the state of the rows
+	ctl  Controller
+	rows map[string]*nodeRow
+}
+
+type nodeRow struct {
+	info   *widget.Label
+	button *widget.Button
+	state  State
+}
+
+// ShowNodes groups the chains by node if the profile has more than one.
+// The chains of the other nodes are listed below the ones of this node.
+func (mui *MainUI) ShowNodes() {
+	nodes, err := ListNodes(profile)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	if len(nodes) < 2 {
+		return
+	}
+	mui.chainList.Objects = append([]fyne.CanvasObject{nodeHeader(node)}, mui.chainList.Objects...)
+	quit := make(chan struct{})
+	for _, n := range nodes {
+		if n == node {
+			continue
+		}
+		g := &nodeGroup{mui: mui, n: n, box: container.NewVBox(nodeHeader(n)), quit: quit, rows: make(map[string]*nodeRow)}
+		mui.chainList.Add(g.box)
+		go g.connect()
+	}
+	mui.as.w.SetOnClosed(func() { close(quit) })
+	mui.chainList.Refresh()
+}
+
+func nodeHeader(n int) fyne.CanvasObject {
+	l := widget.NewLabel("Node " + strconv.Itoa(n))
+	l.TextStyle = fyne.TextStyle{Bold: true}
+	return l
+}
+
+// connect starts the daemon of the node if needed and keeps the rows up to
+// date until the window is closed. While the daemon doesn't answer it is
+// asked less and less often.
+func (g *nodeGroup) connect() {
+	ctl, err := ConnectDaemon(g.n)
+	if err != nil {
+		g.box.Add(widget.NewLabel(err.Error()))
+		return
+	}
+	g.mu.Lock()
+	g.ctl = ctl
+	g.mu.Unlock()
+	var delay time.Duration
+	failing := false
+	for {
+		select {
+		case <-g.quit:
+			return
+		case <-time.After(delay):
+		}
+		statuses, err := ctl.Status()
+		if err != nil {
+			if !failing {
+				println(fmt.Sprintf("node %d: %s", g.n, err))
+			}
+			failing = true
+			switch delay *= 2; {
+			case delay < nodePollInterval:
+				delay = nodePollInterval
+			case delay > nodePollMaxBackoff:
+				delay = nodePollMaxBackoff
+			}
+			continue
+		}
+		if failing {
+			println(fmt.Sprintf("node %d: daemon answers again", g.n))
+		}
+		failing, delay = false, nodePollInterval
+		g.update(statuses)
+	}
+}
+
+// update shows statuses in the rows of the node.
+func (g *nodeGroup) update(statuses []ChainStatus) {
+	for _, st := range statuses {
+		g.mu.Lock()
+		r, ok := g.rows[st.ID]
+		g.mu.Unlock()
+		if !ok {
+			r = g.addRow(st)
+		}
+		g.mu.Lock()
+		r.state = st.State
+		g.mu.Unlock()
+		r.info.SetText(nodeRowText(st))
+		if st.State == Unknown {
+			r.button.SetText("Launch")
+			r.button.Importance = widget.HighImportance
+		} else {
+			r.button.SetText("Stop")
+			r.button.Importance = widget.MediumImportance
+		}
+		r.button.Refresh()
+	}
+}
+
+func (g *nodeGroup) addRow(st ChainStatus) *nodeRow {
+	r := &nodeRow{info: widget.NewLabel("")}
+	title := widget.NewLabel(st.Name)
+	title.TextStyle = fyne.TextStyle{Bold: true}
+	id := st.ID
+	r.button = widget.NewButton("Launch", func() {
+		g.mu.Lock()
+		ctl, running := g.ctl, r.state != Unknown
+		g.mu.Unlock()
+		go func() {
+			var err error
+			if running {
+				err = ctl.Stop(id, nil)
+			} else {
+				err = ctl.Start(id)
+			}
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("node %d: %w", g.n, err), g.mui.as.w)
+			}
+		}()
+	})
+
+	bck := NewThemedRectangle(theme.ColorNameMenuBackground)
+	bck.CornerRadius = 8
+	bck.Refresh()
+	g.box.Add(container.NewStack(bck, container.NewBorder(nil, nil, title, r.button, r.info)))
+	g.mu.Lock()
+	g.rows[id] = r
+	g.mu.Unlock()
+	return r
+}
+
+// nodeRowText shows what tells the nodes apart: height, tip and ports.
+func nodeRowText(st ChainStatus) string {
+	if st.State == Unknown {
+		return fmt.Sprintf("stopped, RPC port %d", st.RPCPort)
+	}
+	tip := st.BestBlockHash
+	if len(tip) > 12 {
+		tip = tip[:12]
+	}
+	return fmt.Sprintf("Blocks: %d  Tip: %s  RPC port %d", st.Height, tip, st.RPCPort)
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	PortRPC = "rpc"
+	PortP2P = "p2p"
+)
+
+// PortCheck is a port a chain is configured to listen on and whether
+// something else has it.
+type PortCheck struct {
+	ChainID string      `json:"chain"`
+	Use     string      `json:"use"` // PortRPC or PortP2P
+	Port    int         `json:"port"`
+	Source  ValueSource `json:"source,omitempty"`
+	// Set if another process listens on the port, Owner and PID only if it
+	// could be identified
+	InUse bool   `json:"inuse"`
+	Owner string `json:"owner,omitempty"`
+	PID   int    `json:"pid,omitempty"`
+	// Another chain configured with the same port
+	SharedWith string `json:"sharedwith,omitempty"`
+}
+
+// Conflict tells whether the chain can't get the port.
+func (p PortCheck) Conflict() bool {
+	return p.InUse || p.SharedWith != ""
+}
+
+func (p PortCheck) String() string {
+	switch {
+	case p.InUse && p.Owner != "":
+		return fmt.Sprintf("in use by %s (pid %d)", p.Owner, p.PID)
+	case p.InUse:
+		return "in use by another program"
+	case p.SharedWith != "":
+		return "also used by " + p.SharedWith
+	}
+	return "free"
+}
+
+// PortChange is a port FixPorts moved a chain to.
+type PortChange struct {
+	ChainID string `json:"chain"`
+	Use     string `json:"use"`
+	From    int    `json:"from"`
+	To      int    `json:"to"`
+}
+
+// chainPorts returns the ports cd listens on, the P2P port only if known.
+func chainPorts(cd *ChainData) []PortCheck {
+	ports := []PortCheck{{ChainID: cd.ID, Use: PortRPC, Port: cd.Port, Source: cd.PortSource}}
+	if cd.P2PPort != 0 {
+		ports = append(ports, PortCheck{ChainID: cd.ID, Use: PortP2P, Port: cd.P2PPort, Source: cd.P2PSource})
+	}
+	return ports
+}
+
+// CheckPorts returns the ports of every chain and who else has them. The
+// ports of a running chain are its own and not reported as in use.
+func CheckPorts(as *AppState) []PortCheck {
+	var checks []PortCheck
+	owners := make(map[int]string)
+	for _, id := range chainIDs(as) {
+		cd, _, err := chainByID(as, id)
+		if err != nil {
+			continue
+		}
+		running := as.sup.IsRunning(id)
+		if !running {
+			_, running = runningPID(cd)
+		}
+		for _, p := range chainPorts(cd) {
+			if other, ok := owners[p.Port]; ok {
+				p.SharedWith = other
+			} else {
+				owners[p.Port] = id
+			}
+			if !running && !portFree(p.Port) {
+				p.InUse = true
+				p.PID, p.Owner = portOwner(p.Port)
+			}
+			checks = append(checks, p)
+		}
+	}
+	return checks
+}
+
+// checkChainPorts is the launch preflight for the ports of cd. Ports shared
+// with another chain only fail once that chain has them.
+func checkChainPorts(as *AppState, cd *ChainData) error {
+	for _, p := range CheckPorts(as) {
+		if p.ChainID != cd.ID || !p.InUse {
+			continue
+		}
+		return &LaunchError{
+			ChainID: cd.ID,
+			Kind:    PortInUse,
+			Port:    p.Port,
+			Fix:     fmt.Sprintf("Stop the other program or move %s to free ports: dc-launcher ports --fix %s", cd.ID, cd.ID),
+			Err:     fmt.Errorf("%s port %s", p.Use, p),
+		}
+	}
+	return nil
+}
+
+func portFree(port int) bool {
+	l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+}
+
+// portOwner finds the process listening on port through /proc. Only
+// processes of the same user can be identified.
+func portOwner(port int) (int, string) {
+	inodes := make(map[string]bool)
+	for _, table := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		for _, inode := range listeningInodes(table, port) {
+			inodes[inode] = true
+		}
+	}
+	if len(inodes) == 0 {
+		return 0, ""
+	}
+	procs, _ := filepath.Glob("/proc/[0-9]*/fd/*")
+	for _, fd := range procs {
+		link, err := os.Readlink(fd)
+		if err != nil || !strings.HasPrefix(link, "socket:[") {
+			continue
+		}
+		if !inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] {
+			continue
+		}
+		dir := filepath.Dir(filepath.Dir(fd))
+		pid, _ := strconv.Atoi(filepath.Base(dir))
+		comm, _ := os.ReadFile(filepath.Join(dir, "comm"))
+		return pid, strings.TrimSpace(string(comm))
+	}
+	return 0, ""
+}
+
+// listeningInodes returns the socket inodes listening on port in a
+// /proc/net/tcp style table.
+func listeningInodes(table string, port int) []string {
+	f, err := os.Open(table)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var inodes []string
+	s := bufio.NewScanner(f)
+	s.Scan() // Header
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		// sl local_address rem_address st ... inode, 0A is LISTEN
+		if len(fields) < 10 || fields[3] != "0A" {
+			continue
+		}
+		_, hexPort, _ := strings.Cut(fields[1], ":")
+		if p, err := strconv.ParseInt(hexPort, 16, 32); err == nil && int(p) == port {
+			inodes = append(inodes, fields[9])
+		}
+	}
+	return inodes
+}
+
+// freePort returns the first port after port that no chain uses and
+// nothing listens on.
+func freePort(port int, taken map[int]bool) (int, error) {
+	for p := port + 1; p <= 65535; p++ {
+		if !taken[p] && portFree(p) {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("no free port after %d", port)
+}
+
+// FixPorts moves the chains in ids, or every chain if ids is empty, off
+// ports they can't get. The new ports are written to the conf files and if
+// drivechain moves, sidechains are told with mainchainrpcport. Running
+// chains and ports given on the command line are left alone.
+func FixPorts(as *AppState, ids []string) ([]PortChange, error) {
+	checks := CheckPorts(as)
+	taken := make(map[int]bool)
+	for _, p := range checks {
+		taken[p.Port] = true
+	}
+	fix := make(map[string]bool)
+	for _, id := range ids {
+		if _, _, err := chainByID(as, id); err != nil {
+			return nil, err
+		}
+		fix[id] = true
+	}
+
+	var changes []PortChange
+	var errs []error
+	for _, p := range checks {
+		if !p.Conflict() || len(fix) > 0 && !fix[p.ChainID] {
+			continue
+		}
+		if p.Source == SourceFlag {
+			errs = append(errs, fmt.Errorf("%s: port %d was given on the command line", p.ChainID, p.Port))
+			continue
+		}
+		cd, _, err := chainByID(as, p.ChainID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if as.sup.IsRunning(cd.ID) {
+			continue
+		}
+		port, err := freePort(p.Port, taken)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", cd.ID, err))
+			continue
+		}
+		key := "rpcport"
+		if p.Use == PortP2P {
+			key = "port"
+		}
+		if err := setConfValue(cd, key, strconv.Itoa(port)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", cd.ID, err))
+			continue
+		}
+		taken[port] = true
+		println(fmt.Sprintf("Moved %s %s port from %d to %d", cd.ID, p.Use, p.Port, port))
+		changes = append(changes, PortChange{ChainID: cd.ID, Use: p.Use, From: p.Port, To: port})
+		if err := as.loadChainConf(cd); err != nil {
+			errs = append(errs, err)
+		}
+		as.setChainData(cd)
+		if cd.IsDrivechain && p.Use == PortRPC {
+			errs = append(errs, setMainchainPort(as, port))
+		}
+	}
+	return changes, errors.Join(errs...)
+}
+
+// setMainchainPort points the sidechains that read drivechain's RPC port
+// from their conf at port. Sidechains launched with {mainchain.rpcport}
+// pick it up by themselves.
+func setMainchainPort(as *AppState, port int) error {
+	var errs []error
+	for _, id := range chainIDs(as)[1:] {
+		cd, _, err := chainByID(as, id)
+		if err != nil {
+			continue
+		}
+		switch cd.Kind {
+		case KindBitcoinSidechain, KindBitnames:
+			if err := setConfValue(cd, "mainchainrpcport", strconv.Itoa(port)); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", id, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// pointAtMainchain writes drivechain's RPC port to the conf of a sidechain
+// that is started through a script and can't be given it on the command
+// line.
+func pointAtMainchain(cd *ChainData, port int) error {
+	c, err := chainConf(cd)
+	if err != nil {
+		return err
+	}
+	if v, ok, err := c.GetInt("mainchainrpcport"); err == nil && ok && v == port {
+		return nil
+	}
+	return setConfValue(cd, "mainchainrpcport", strconv.Itoa(port))
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ValueSource is where the launcher got a chain setting from.
+type ValueSource string
+
+// In order of precedence
+const (
+	SourceFlag      ValueSource = "flag"      // --rpcport or --slot on the command line
+	SourceConf      ValueSource = "conf"      // The chain's conf file
+	SourceOverride  ValueSource = "override"  // overrides.json
+	SourceCatalogue ValueSource = "catalogue" // The chain catalogue
+	SourceDefault   ValueSource = "default"   // Built into the chain
+)
+
+func (s ValueSource) String() string {
+	switch s {
+	case SourceFlag:
+		return "command line"
+	case SourceConf:
+		return "conf file"
+	case SourceOverride:
+		return "overrides.json"
+	case SourceDefault:
+		return "chain default"
+	}
+	return "catalogue default"
+}
+
+// providerSource tells whether field of cp is the catalogue's or was
+// overridden.
+func providerSource(cp ChainProvider, field string) ValueSource {
+	if cp.Overridden[field] {
+		return SourceOverride
+	}
+	return SourceCatalogue
+}
+
+// loadChainConf sets the RPC port and slot of cd from, in order of
+// precedence, the command line, its conf file, overrides.json and the
+// catalogue, and reads the rest of the conf. Default ports are moved for
+// the launcher network.
+func (as *AppState) loadChainConf(cd *ChainData) error {
+	cp := as.cp[cd.ID]
+	cd.Port, cd.PortSource = as.network.Port(cp.DefaultPort), providerSource(cp, "defaultPort")
+	if !cd.IsDrivechain {
+		cd.Slot, cd.SlotSource = cp.DefaultSlot, providerSource(cp, "defaultSlot")
+	}
+	cd.P2PPort, cd.P2PSource = 0, ""
+	if err := loadConf(cd); err != nil {
+		return err
+	}
+	if cd.P2PPort == 0 {
+		if port, source, ok := defaultP2PPort(cd, cp, as.portOffset); ok {
+			cd.P2PPort, cd.P2PSource = port, source
+		}
+	}
+	if port, ok := as.flagPorts[cd.ID]; ok {
+		cd.Port, cd.PortSource = port, SourceFlag
+	}
+	if slot, ok := as.flagSlots[cd.ID]; ok {
+		if !takesSlot(cd.Kind, cp.Launch) {
+			return fmt.Errorf("--slot %s=%d: %s is not started with a slot", cd.ID, slot, cd.ID)
+		}
+		cd.Slot, cd.SlotSource = slot, SourceFlag
+	}
+	return nil
+}
+
+// takesSlot tells whether chains of kind are started with their slot:
+// bitcoind style sidechains get -slot, other chains only through {slot} in
+// their launch template.
+func takesSlot(kind ChainKind, lt *LaunchTemplate) bool {
+	if kind == KindBitcoinSidechain {
+		return true
+	}
+	if lt == nil {
+		return false
+	}
+	uses := strings.Contains(lt.Exec, "{slot}")
+	for _, arg := range lt.Args {
+		uses = uses || strings.Contains(arg, "{slot}")
+	}
+	for _, v := range lt.Env {
+		uses = uses || strings.Contains(v, "{slot}")
+	}
+	return uses
+}
+
+// defaultP2PPort returns the P2P port of a Bitcoin Core based chain
+// without a port setting, moved by offset: the catalogue's defaultP2PPort,
+// moved for the network like RPC ports, or for the mainchains Bitcoin
+// Core's default for the network. Ports from overrides.json are used as
+// they are.
+func defaultP2PPort(cd *ChainData, cp ChainProvider, offset int) (int, ValueSource, bool) {
+	if !usesNetworkConf(cd) {
+		return 0, "", false
+	}
+	if cp.DefaultP2PPort != 0 {
+		source := providerSource(cp, "defaultP2PPort")
+		if source == SourceOverride {
+			return cp.DefaultP2PPort, source, true
+		}
+		n, _ := networkOf(cd.Network)
+		return n.Port(cp.DefaultP2PPort) + offset, source, true
+	}
+	switch cd.Kind {
+	case KindDrivechain, KindLatestCore:
+	default:
+		return 0, "", false
+	}
+	port, ok := map[string]int{
+		"main":     8333,
+		"test":     18333,
+		"testnet4": 48333,
+		"signet":   38333,
+		"regtest":  18444,
+	}[cd.Network]
+	return port + offset, SourceDefault, ok
+}
+
+// portArgs passes the RPC and P2P ports to bitcoind style chains, and the
+// slot to bitcoind style sidechains, if they don't come from their conf
+// file, command line arguments beat the conf.
+func portArgs(cd *ChainData) []string {
+	var args []string
+	switch cd.Kind {
+	case KindDrivechain, KindBitcoinSidechain, KindLatestCore:
+	default:
+		return nil
+	}
+	if cd.PortSource != SourceConf {
+		args = append(args, "-rpcport="+strconv.Itoa(cd.Port))
+	}
+	if cd.P2PPort != 0 && cd.P2PSource != SourceConf {
+		args = append(args, "-port="+strconv.Itoa(cd.P2PPort))
+	}
+	if cd.Kind == KindBitcoinSidechain && cd.SlotSource != SourceConf {
+		args = append(args, "-slot="+strconv.Itoa(cd.Slot))
+	}
+	return args
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLoadChainConfPrecedence(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		conf       string
+		overridden bool
+		flagPort   int
+		flagSlot   int
+		port       int
+		portSource ValueSource
+		slot       int
+		slotSource ValueSource
+	}{
+		{"catalogue", "", false, 0, 0, 8272, SourceCatalogue, 3, SourceCatalogue},
+		{"override", "", true, 0, 0, 8272, SourceOverride, 3, SourceOverride},
+		{"conf beats override", "rpcport=9000\nslot=5\n", true, 0, 0, 9000, SourceConf, 5, SourceConf},
+		{"flag beats conf", "rpcport=9000\nslot=5\n", false, 9100, 6, 9100, SourceFlag, 6, SourceFlag},
+		{"flag port only", "slot=5\n", false, 9100, 0, 9100, SourceFlag, 5, SourceConf},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "testchain.conf"), []byte(tt.conf), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			cp := ChainProvider{ID: "testchain", DefaultPort: 8272, DefaultSlot: 3}
+			if tt.overridden {
+				cp.Overridden = map[string]bool{"defaultPort": true, "defaultSlot": true}
+			}
+			as := &AppState{
+				cp:        map[string]ChainProvider{cp.ID: cp},
+				flagPorts: make(map[string]int),
+				flagSlots: make(map[string]int),
+			}
+			if tt.flagPort != 0 {
+				as.flagPorts[cp.ID] = tt.flagPort
+			}
+			if tt.flagSlot != 0 {
+				as.flagSlots[cp.ID] = tt.flagSlot
+			}
+			cd := &ChainData{ID: cp.ID, Kind: KindBitcoinSidechain, ConfDir: dir, ConfName: "testchain.conf"}
+			if err := as.loadChainConf(cd); err != nil {
+				t.Fatal(err)
+			}
+			if cd.Port != tt.port || cd.PortSource != tt.portSource {
+				t.Errorf("port = %d from %s, want %d from %s", cd.Port, cd.PortSource, tt.port, tt.portSource)
+			}
+			if cd.Slot != tt.slot || cd.SlotSource != tt.slotSource {
+				t.Errorf("slot = %d from %s, want %d from %s", cd.Slot, cd.SlotSource, tt.slot, tt.slotSource)
+			}
+			// The conf's own rpcport isn't repeated on the command line
+			passed := false
+			for _, arg := range portArgs(cd) {
+				if strings.HasPrefix(arg, "-rpcport=") {
+					passed = arg == "-rpcport="+strconv.Itoa(tt.port)
+				}
+			}
+			if passed != (tt.portSource != SourceConf) {
+				t.Errorf("portArgs = %v with the port from %s", portArgs(cd), tt.portSource)
+			}
+			passed = false
+			for _, arg := range portArgs(cd) {
+				if strings.HasPrefix(arg, "-slot=") {
+					passed = arg == "-slot="+strconv.Itoa(tt.slot)
+				}
+			}
+			if passed != (tt.slotSource != SourceConf) {
+				t.Errorf("portArgs = %v with the slot from %s", portArgs(cd), tt.slotSource)
+			}
+		})
+	}
+}
+
+func TestFlagSlotNeedsSlotArgument(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		kind   ChainKind
+		launch *LaunchTemplate
+		ok     bool
+	}{
+		{"bitcoin sidechain", KindBitcoinSidechain, nil, true},
+		{"drivechain", KindDrivechain, nil, false},
+		{"thunder", KindThunder, nil, false},
+		{"bitnames", KindBitnames, nil, false},
+		{"template with slot", KindThunder, &LaunchTemplate{Args: []string{"--slot", "{slot}"}}, true},
+		{"bitcoin sidechain with a template", KindBitcoinSidechain, &LaunchTemplate{Args: []string{"-conf={conffile}"}}, true},
+		{"template without slot", KindThunder, &LaunchTemplate{Args: []string{"-d", "{datadir}"}}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "testchain.conf"), nil, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			cp := ChainProvider{ID: "testchain", DefaultPort: 8272, Launch: tt.launch}
+			as := &AppState{
+				cp:        map[string]ChainProvider{cp.ID: cp},
+				flagSlots: map[string]int{cp.ID: 6},
+			}
+			cd := &ChainData{ID: cp.ID, Kind: tt.kind, ConfDir: dir, ConfName: "testchain.conf"}
+			err := as.loadChainConf(cd)
+			if (err == nil) != tt.ok {
+				t.Errorf("loadChainConf = %v", err)
+			}
+		})
+	}
+}
+
+func TestP2PPort(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		kind       ChainKind
+		conf       string
+		catalogue  int
+		overridden bool
+		port       int
+		source     ValueSource
+		passed     bool
+	}{
+		{"drivechain", KindDrivechain, "regtest=1\n", 0, false, 18444 + 10, SourceDefault, true},
+		{"drivechain on signet", KindDrivechain, "signet=1\n", 0, false, 38333 + 10, SourceDefault, true},
+		{"sidechain", KindBitcoinSidechain, "regtest=1\n", 19100, false, 19100 + 10, SourceCatalogue, true},
+		{"sidechain on signet", KindBitcoinSidechain, "signet=1\n", 19100, false, 21100 + 10, SourceCatalogue, true},
+		{"sidechain override", KindBitcoinSidechain, "regtest=1\n", 19200, true, 19200, SourceOverride, true},
+		{"sidechain conf", KindBitcoinSidechain, "regtest=1\n[regtest]\nport=19300\n", 19100, false, 19300, SourceConf, false},
+		{"sidechain without default", KindBitcoinSidechain, "regtest=1\n", 0, false, 0, "", false},
+		{"bitnames", KindBitnames, "regtest=1\n", 19400, false, 19400 + 10, SourceCatalogue, false},
+		{"thunder", KindThunder, "", 19500, false, 0, "", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "testchain.conf"), []byte(tt.conf), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			cp := ChainProvider{ID: "testchain", DefaultPort: 8272, DefaultP2PPort: tt.catalogue}
+			if tt.overridden {
+				cp.Overridden = map[string]bool{"defaultP2PPort": true}
+			}
+			as := &AppState{cp: map[string]ChainProvider{cp.ID: cp}, portOffset: 10}
+			cd := &ChainData{ID: cp.ID, Kind: tt.kind, ConfDir: dir, ConfName: "testchain.conf"}
+			if err := as.loadChainConf(cd); err != nil {
+				t.Fatal(err)
+			}
+			if cd.P2PPort != tt.port || cd.P2PSource != tt.source {
+				t.Errorf("P2P port = %d from %q, want %d from %q", cd.P2PPort, cd.P2PSource, tt.port, tt.source)
+			}
+			passed := false
+			for _, arg := range portArgs(cd) {
+				passed = passed || arg == "-port="+strconv.Itoa(tt.port)
+			}
+			if passed != tt.passed {
+				t.Errorf("portArgs = %v", portArgs(cd))
+			}
+			checks := chainPorts(cd)
+			if got := checks[len(checks)-1]; (got.Use == PortP2P) != (tt.port != 0) || tt.port != 0 && got.Port != tt.port {
+				t.Errorf("chainPorts = %+v", checks)
+			}
+		})
+	}
+}
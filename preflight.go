@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+type LaunchErrorKind int
+
+const (
+	LaunchFailed LaunchErrorKind = iota
+	BinaryMissing
+	PermissionDenied
+	PortInUse
+	DependencyMissing
+	DownloadFailed
+	IntegrityFailed
+)
+
+func (k LaunchErrorKind) String() string {
+	switch k {
+	case BinaryMissing:
+		return "binary missing"
+	case PermissionDenied:
+		return "permission denied"
+	case PortInUse:
+		return "port already in use"
+	case DependencyMissing:
+		return "dependency missing"
+	case DownloadFailed:
+		return "download failed"
+	case IntegrityFailed:
+		return "integrity check failed"
+	}
+	return "launch failed"
+}
+
+// LaunchError is returned by LaunchChain when a chain could not be started.
+// Fix is a suggestion shown to the user next to the error.
+type LaunchError struct {
+	ChainID string
+	Kind    LaunchErrorKind
+	Path    string
+	Port    int
+	Fix     string
+	Err     error
+}
+
+func (e *LaunchError) Error() string {
+	msg := fmt.Sprintf("could not launch %s: %s", e.ChainID, e.Kind)
+	if e.Path != "" {
+		msg += " (" + e.Path + ")"
+	}
+	if e.Port != 0 {
+		msg += fmt.Sprintf(" (port %d)", e.Port)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *LaunchError) Unwrap() error {
+	return e.Err
+}
+
+type libDependency struct {
+	Lib string
+	Fix string
+}
+
+// Shared libraries a chain needs that are not shipped with it
+var chainDependencies = map[string][]libDependency{
+	"bitnames": {{Lib: "libQt5Core.so.5", Fix: "sudo apt install qtbase5-dev"}},
+}
+
+var libDirs = []string{
+	"/lib",
+	"/lib64",
+	"/usr/lib",
+	"/usr/lib64",
+	"/usr/local/lib",
+	"/lib/x86_64-linux-gnu",
+	"/usr/lib/x86_64-linux-gnu",
+	"/lib/aarch64-linux-gnu",
+	"/usr/lib/aarch64-linux-gnu",
+}
+
+func hasLibrary(name string) bool {
+	for _, dir := range libDirs {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// checkExecutable makes sure path exists and can be executed.
+func checkExecutable(cd *ChainData, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return launchErrorFromStart(cd, path, err)
+	}
+	if info.IsDir() || info.Mode().Perm()&0o111 == 0 {
+		return &LaunchError{
+			ChainID: cd.ID,
+			Kind:    PermissionDenied,
+			Path:    path,
+			Fix:     "Make the binary executable: chmod +x " + path,
+		}
+	}
+	return nil
+}
+
+func checkDependencies(cd *ChainData) error {
+	for _, dep := range chainDependencies[cd.ID] {
+		if !hasLibrary(dep.Lib) {
+			return &LaunchError{
+				ChainID: cd.ID,
+				Kind:    DependencyMissing,
+				Path:    dep.Lib,
+				Fix:     "You likely need to run: " + dep.Fix,
+			}
+		}
+	}
+	return nil
+}
+
+// PreflightChain checks everything that commonly prevents a chain from
+// starting, before actually starting it.
+func PreflightChain(as *AppState, cd *ChainData, executable string) error {
+	if err := checkExecutable(cd, executable); err != nil {
+		return err
+	}
+	if err := checkDependencies(cd); err != nil {
+		return err
+	}
+	return checkChainPorts(as, cd)
+}
+
+// launchErrorFromStart turns an error from exec into a LaunchError.
+func launchErrorFromStart(cd *ChainData, path string, err error) error {
+	var le *LaunchError
+	if errors.As(err, &le) {
+		return err
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return &LaunchError{
+			ChainID: cd.ID,
+			Kind:    BinaryMissing,
+			Path:    path,
+			Fix:     "Use File > Reset Everything or restart the launcher to reinstall the chain",
+			Err:     err,
+		}
+	case errors.Is(err, fs.ErrPermission):
+		return &LaunchError{
+			ChainID: cd.ID,
+			Kind:    PermissionDenied,
+			Path:    path,
+			Fix:     "Make the binary executable: chmod +x " + path,
+			Err:     err,
+		}
+	case errors.Is(err, syscall.ENOEXEC):
+		return &LaunchError{
+			ChainID: cd.ID,
+			Kind:    LaunchFailed,
+			Path:    path,
+			Fix:     "The binary was built for a different platform",
+			Err:     err,
+		}
+	}
+	return &LaunchError{ChainID: cd.ID, Kind: LaunchFailed, Path: path, Err: err}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// Profiles are separate launcher environments, e.g. a demo next to a long
+// running test setup, each with its own chain data, ports, credentials and
+// overrides. The default profile lives in the home directory, named
+// profiles in ~/.dclauncher/profiles/<name> with the same layout.
+const (
+	defaultProfile = "default"
+	profilesDir    = "profiles"
+	// How far apart the default ports of profiles and nodes are
+	profilePortStep = 20
+)
+
+// profile is the profile of this process, chosen at startup.
+var profile = defaultProfile
+
+var profileNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+func validProfileName(name string) error {
+	if !profileNameRe.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q, use lowercase letters, digits, - and _", name)
+	}
+	return nil
+}
+
+// sharedLauncherDir holds what all profiles share: the named profiles and
+// the download cache.
+func sharedLauncherDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".dclauncher"), nil
+}
+
+// profileRoot returns the directory the launcher and chain directories of
+// profile name are in.
+func profileRoot(name string) (string, error) {
+	if name == defaultProfile {
+		return os.UserHomeDir()
+	}
+	dir, err := sharedLauncherDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, profilesDir, name), nil
+}
+
+// profileHome returns the root of the profile and node of this process.
+func profileHome() (string, error) {
+	return nodeRoot(profile, node)
+}
+
+// ListProfiles returns the default profile followed by the named ones.
+func ListProfiles() ([]string, error) {
+	dir, err := sharedLauncherDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, profilesDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && validProfileName(e.Name()) == nil {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return append([]string{defaultProfile}, names...), nil
+}
+
+// SetProfile makes name the profile of this process, the default profile
+// if name is empty. Named profiles must have been created.
+func SetProfile(name string) error {
+	if name == "" || name == defaultProfile {
+		profile = defaultProfile
+		return nil
+	}
+	if err := validProfileName(name); err != nil {
+		return err
+	}
+	root, err := profileRoot(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return fmt.Errorf("no profile %q, create it with: dc-launcher profiles create %s", name, name)
+	}
+	profile = name
+	return nil
+}
+
+// CreateProfile creates profile name. Its default ports are moved past
+// those of every other profile so their chains can run side by side.
+func CreateProfile(name string) (LauncherSettings, error) {
+	var s LauncherSettings
+	if name == defaultProfile {
+		return s, errors.New("the default profile always exists")
+	}
+	if err := validProfileName(name); err != nil {
+		return s, err
+	}
+	root, err := profileRoot(name)
+	if err != nil {
+		return s, err
+	}
+	if _, err := os.Stat(root); err == nil {
+		return s, fmt.Errorf("profile %q already exists", name)
+	}
+
+	if s.PortOffset, err = freePortOffset(); err != nil {
+		return s, err
+	}
+
+	dir := filepath.Join(root, ".dclauncher")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return s, err
+	}
+	println(fmt.Sprintf("Created profile %s in %s, ports moved by %d", name, root, s.PortOffset))
+	return s, writeSettings(dir, s)
+}
+
+// freePortOffset returns the lowest port offset no profile or node uses.
+func freePortOffset() (int, error) {
+	names, err := ListProfiles()
+	if err != nil {
+		return 0, err
+	}
+	used := make(map[int]bool)
+	for _, name := range names {
+		nodes, err := ListNodes(name)
+		if err != nil {
+			return 0, err
+		}
+		for _, n := range nodes {
+			root, err := nodeRoot(name, n)
+			if err != nil {
+				return 0, err
+			}
+			s, err := readSettings(filepath.Join(root, ".dclauncher"))
+			if err != nil {
+				println(err.Error())
+			}
+			used[s.PortOffset] = true
+		}
+	}
+	offset := profilePortStep
+	for used[offset] {
+		offset += profilePortStep
+	}
+	return offset, nil
+}
+
+// profileTitle is the window title for the profile of this process.
+func profileTitle(title string) string {
+	if profile == defaultProfile {
+		return title
+	}
+	return fmt.Sprintf("%s (%s)", title, profile)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ShowProfileChooser fills the window with a choice of profiles, creating
+// new ones included. open is called once the profile is selected.
+func ShowProfileChooser(as *AppState, profiles []string, open func()) {
+	sel := widget.NewSelect(profiles, nil)
+	sel.SetSelected(defaultProfile)
+
+	name := widget.NewEntry()
+	name.SetPlaceHolder("New profile name")
+	create := widget.NewButton("Create", func() {
+		if _, err := CreateProfile(name.Text); err != nil {
+			dialog.ShowError(err, as.w)
+			return
+		}
+		if profiles, err := ListProfiles(); err == nil {
+			sel.Options = profiles
+		}
+		sel.SetSelected(name.Text)
+		name.SetText("")
+	})
+
+	openButton := widget.NewButton("Open", func() {
+		if err := SetProfile(sel.Selected); err != nil {
+			dialog.ShowError(err, as.w)
+			return
+		}
+		as.w.SetTitle(profileTitle("Drivechain Launcher"))
+		open()
+	})
+	openButton.Importance = widget.HighImportance
+
+	title := widget.NewLabel("Choose a profile")
+	title.TextStyle = fyne.TextStyle{Bold: true}
+	as.w.SetContent(container.NewCenter(container.NewVBox(
+		title,
+		sel,
+		container.NewBorder(nil, nil, nil, create, name),
+		openButton,
+	)))
+	as.w.Resize(fyne.NewSize(540, 880))
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"dc-launcher/archive"
+)
+
+// ResetScope is what ResetChain deletes.
+type ResetScope string
+
+const (
+	ResetChainData ResetScope = "chain"  // Blocks, state and indexes, the wallet is kept
+	ResetWallet    ResetScope = "wallet" // Only the wallet
+	ResetAll       ResetScope = "all"    // The whole data directory and conf, installed versions excepted
+)
+
+func ParseResetScope(s string) (ResetScope, error) {
+	switch ResetScope(s) {
+	case ResetChainData, ResetWallet, ResetAll:
+		return ResetScope(s), nil
+	}
+	return "", fmt.Errorf("unknown reset scope %q, expected chain, wallet or all", s)
+}
+
+type ResetOptions struct {
+	Scope ResetScope `json:"scope"`
+	// Archive the files to the backups directory before deleting them
+	Backup bool `json:"backup,omitempty"`
+	// Only list what would be deleted
+	DryRun bool `json:"dryrun,omitempty"`
+}
+
+// ResetReport lists what a reset deleted, or would delete on a dry run.
+type ResetReport struct {
+	ChainID string     `json:"chain"`
+	Scope   ResetScope `json:"scope"`
+	Paths   []string   `json:"paths"`
+	Backup  string     `json:"backup,omitempty"`
+	DryRun  bool       `json:"dryrun,omitempty"`
+}
+
+const backupsDirName = "backups"
+
+// backupsDir holds the archives of resets and wallet backups.
+func backupsDir() (string, error) {
+	dir, err := launcherDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, backupsDirName), nil
+}
+
+// backupName is the file name of a backup of cd, what tells the kind of
+// backup apart.
+func backupName(cd *ChainData, what string, ext string) string {
+	return fmt.Sprintf("%s-%s-%s%s", cd.ID, what, time.Now().Format("20060102-150405"), ext)
+}
+
+// launcherFiles are the files in a chain's data directory that belong to
+// the launcher or the installed binaries rather than the chain's data.
+func launcherFiles(cd *ChainData) []string {
+	return []string{
+		cd.ConfName,
+		cd.BinName,
+		versionsDirName,
+		installManifestName,
+		filepath.Base(chainPIDFile(cd)),
+		filepath.Base(chainLogFile(cd)),
+	}
+}
+
+// existingPaths returns the names in dir that exist.
+func existingPaths(dir string, names []string) []string {
+	if dir == "" {
+		return nil
+	}
+	var paths []string
+	for _, name := range names {
+		p := filepath.Join(dir, name)
+		if _, err := os.Lstat(p); err == nil {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// pathsExcept returns everything in dir but the names in except.
+func pathsExcept(dir string, except []string) ([]string, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	skip := make(map[string]bool, len(except))
+	for _, name := range except {
+		skip[name] = true
+	}
+	var paths []string
+	for _, e := range entries {
+		if !skip[e.Name()] {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	return paths, nil
+}
+
+// resetPaths returns what a reset of cd deletes.
+func resetPaths(cd *ChainData, scope ResetScope) ([]string, error) {
+	switch scope {
+	case ResetChainData:
+		return cd.Driver().ChainDataPaths(cd)
+	case ResetWallet:
+		return walletPaths(cd), nil
+	}
+	// Installed versions stay, they are in the download cache and the
+	// chain would have to be installed again
+	paths, err := pathsExcept(cd.ConfDir, []string{versionsDirName, installManifestName})
+	if err != nil {
+		return nil, err
+	}
+	// A datadir set outside the chain's directory
+	if cd.NetDir != "" && !isWithin(cd.ConfDir, cd.NetDir) {
+		paths = append(paths, existingPaths(filepath.Dir(cd.NetDir), []string{filepath.Base(cd.NetDir)})...)
+	}
+	return paths, nil
+}
+
+// walletPaths returns the wallet files of cd that exist.
+func walletPaths(cd *ChainData) []string {
+	return existingPaths(cd.Driver().WalletFiles(cd))
+}
+
+func isWithin(dir string, p string) bool {
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+// chainAnswers tells whether the chain still answers RPC calls.
+func chainAnswers(cd *ChainData) bool {
+	if !cd.Driver().SupportsRPC() {
+		return false
+	}
+	ctx, cancel := rpcContext()
+	defer cancel()
+	_, err := cd.RPCClient().Do(ctx, "getblockcount")
+	return err == nil
+}
+
+// chainProcessAlive tells whether a process of cd is still around: one
+// the launcher knows of, or one holding the lock on the data directory.
+func chainProcessAlive(as *AppState, cd *ChainData) bool {
+	if as.sup.IsRunning(cd.ID) {
+		return true
+	}
+	if _, ok := runningPID(cd); ok {
+		return true
+	}
+	return dataDirLocked(cd)
+}
+
+// dataDirLocked tells whether a Bitcoin Core based chain, which holds a
+// POSIX lock on .lock in its network directory while it runs, is running.
+func dataDirLocked(cd *ChainData) bool {
+	if cd.NetDir == "" || !usesNetworkConf(cd) {
+		return false
+	}
+	f, err := os.Open(filepath.Join(cd.NetDir, ".lock"))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	lk := syscall.Flock_t{Type: syscall.F_WRLCK}
+	if err := syscall.FcntlFlock(f.Fd(), syscall.F_GETLK, &lk); err != nil {
+		return false
+	}
+	return lk.Type != syscall.F_UNLCK
+}
+
+// waitExited waits up to timeout until the process of cd is gone and the
+// chain no longer answers RPC calls, so no file is deleted from under it.
+func waitExited(as *AppState, cd *ChainData, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if !chainProcessAlive(as, cd) && !chainAnswers(cd) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s is still running after %v, nothing was deleted", cd.ID, timeout)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// stopAndWait stops cd if it runs and waits for its process to exit. It
+// returns the chains it stopped, drivechain before the sidechains that were
+// stopped along with it.
+func stopAndWait(as *AppState, cd *ChainData, cs *ChainState) ([]string, error) {
+	if !chainProcessAlive(as, cd) && !chainAnswers(cd) {
+		return nil, waitExited(as, cd, exitTimeout(cd))
+	}
+	stopped := []string{cd.ID}
+	if cd.IsDrivechain {
+		for _, scd := range as.sidechains() {
+			if chainProcessAlive(as, &scd) {
+				stopped = append(stopped, scd.ID)
+			}
+		}
+	}
+	if err := StopChain(cd, cs, as, nil); err != nil {
+		return nil, err
+	}
+	return stopped, waitExited(as, cd, exitTimeout(cd))
+}
+
+func exitTimeout(cd *ChainData) time.Duration {
+	if cd.StopTimeout <= 0 {
+		return defaultStopTimeout
+	}
+	return cd.StopTimeout
+}
+
+// ResetChain deletes the chain data, the wallet or everything of chain id.
+// A running chain is stopped first, stopping drivechain stops the
+// sidechains too, and nothing is deleted until its process exited. A reset
+// of everything writes a fresh conf keeping the RPC credentials, which the
+// sidechains may share.
+func ResetChain(as *AppState, id string, opts ResetOptions) (ResetReport, error) {
+	report := ResetReport{ChainID: id, Scope: opts.Scope, DryRun: opts.DryRun}
+	if _, err := ParseResetScope(string(opts.Scope)); err != nil {
+		return report, err
+	}
+	cd, cs, err := chainByID(as, id)
+	if err != nil {
+		return report, err
+	}
+	if report.Paths, err = resetPaths(cd, opts.Scope); err != nil {
+		return report, err
+	}
+	if opts.DryRun || len(report.Paths) == 0 {
+		return report, nil
+	}
+
+	if _, err := stopAndWait(as, cd, cs); err != nil {
+		return report, err
+	}
+
+	if opts.Backup {
+		if report.Backup, err = archivePaths(cd, "reset-"+string(opts.Scope), report.Paths); err != nil {
+			return report, fmt.Errorf("backup failed, nothing was deleted: %w", err)
+		}
+	}
+
+	var errs []error
+	for _, p := range report.Paths {
+		println("Deleting " + p)
+		if err := os.RemoveAll(p); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if opts.Scope == ResetAll {
+		errs = append(errs, reinitChain(as, cd))
+	}
+	as.setChainData(cd)
+	as.Refresh()
+	return report, errors.Join(errs...)
+}
+
+// archivePaths writes paths to a tar.gz in the backups directory, with
+// their full paths so they can be put back where they were.
+func archivePaths(cd *ChainData, what string, paths []string) (string, error) {
+	dir, err := backupsDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	dst := filepath.Join(dir, backupName(cd, what, ".tar.gz"))
+	println("Backing up " + cd.ID + " to " + dst)
+	return dst, archive.CreateTarGz(dst, string(filepath.Separator), paths)
+}
+
+// reinitChain sets cd up again like a fresh install after its directory
+// was deleted.
+func reinitChain(as *AppState, cd *ChainData) error {
+	user, pass := cd.RPCUser, cd.RPCPass
+	if err := os.MkdirAll(cd.ConfDir, 0o755); err != nil {
+		return err
+	}
+	if err := cd.Driver().WriteDefaultConf(as.cp[cd.ID], cd); err != nil {
+		return err
+	}
+	cd.InstallDir = cd.ConfDir
+	cd.BinDir = cd.Driver().BinDir(cd.ConfDir)
+	if m, err := loadManifest(cd); err == nil {
+		applyManifest(cd, m)
+	}
+	if err := as.loadChainConf(cd); err != nil {
+		return err
+	}
+	if err := as.switchNetwork(cd); err != nil {
+		return err
+	}
+	if pass != "" && pass != weakRPCPassword {
+		setCredentials(as, cd, user, pass)
+	}
+	secureChainCredentials(as, cd)
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestParseResetScope(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want ResetScope
+		ok   bool
+	}{
+		{"chain", ResetChainData, true},
+		{"wallet", ResetWallet, true},
+		{"all", ResetAll, true},
+		{"", "", false},
+		{"All", "", false},
+		{"blocks", "", false},
+	} {
+		got, err := ParseResetScope(tt.in)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("ParseResetScope(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestResetPaths(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "testchain")
+	netDir := filepath.Join(dir, "regtest")
+	for _, p := range []string{
+		"testchain.conf", "testchaind", "debug.log",
+		"regtest/blocks/blk00000.dat", "regtest/chainstate/CURRENT",
+		"regtest/wallets/wallet.dat", "regtest/peers.dat",
+		"versions/1.0/testchaind", installManifestName,
+	} {
+		p = filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cd := &ChainData{
+		ID:       "testchain",
+		Kind:     KindBitcoinSidechain,
+		BinName:  "testchaind",
+		ConfDir:  dir,
+		ConfName: "testchain.conf",
+		NetDir:   netDir,
+	}
+	for _, tt := range []struct {
+		scope ResetScope
+		want  []string
+	}{
+		{ResetChainData, []string{"regtest/blocks", "regtest/chainstate", "regtest/peers.dat"}},
+		{ResetWallet, []string{"regtest/wallets"}},
+		{ResetAll, []string{"debug.log", "regtest", "testchain.conf", "testchaind"}},
+	} {
+		paths, err := resetPaths(cd, tt.scope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, p := range paths {
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, filepath.ToSlash(rel))
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("resetPaths(%s) = %v, want %v", tt.scope, got, tt.want)
+		}
+	}
+}
+
+func TestResetEverythingKeepsBackups(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir, err := backupsDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	backup := filepath.Join(dir, "testchain-wallet-20240101-000000.tar.gz")
+	if err := os.WriteFile(backup, []byte("wallet"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ResetEverything(testAppState()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(backup); err != nil {
+		t.Errorf("backup gone after the reset: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+var resetChoices = []struct {
+	label string
+	scope ResetScope
+}{
+	{"Chain data, keep the wallet", ResetChainData},
+	{"Wallet only", ResetWallet},
+	{"Everything but the installed versions, conf included", ResetAll},
+}
+
+// NewResetButton returns the row button opening ShowChainReset.
+func (mui *MainUI) NewResetButton(cp ChainProvider) *widget.Button {
+	b := widget.NewButton("Reset", func() {
+		mui.ShowChainReset(cp)
+	})
+	b.Importance = widget.LowImportance
+	return b
+}
+
+// ShowChainReset lets the user choose what of a chain to delete, lists the
+// files that go and resets the chain once confirmed.
+func (mui *MainUI) ShowChainReset(cp ChainProvider) {
+	var labels []string
+	for _, c := range resetChoices {
+		labels = append(labels, c.label)
+	}
+	rg := widget.NewRadioGroup(labels, nil)
+	rg.SetSelected(labels[0])
+	backup := widget.NewCheck("Back up the files first", nil)
+	backup.SetChecked(true)
+
+	dialog.ShowCustomConfirm("Reset "+cp.Name, "Next", "Cancel", container.NewVBox(rg, backup), func(ok bool) {
+		if !ok {
+			return
+		}
+		opts := ResetOptions{Backup: backup.Checked, DryRun: true}
+		for _, c := range resetChoices {
+			if c.label == rg.Selected {
+				opts.Scope = c.scope
+			}
+		}
+		report, err := mui.ctl.ResetChain(cp.ID, opts)
+		if err != nil {
+			dialog.ShowError(err, mui.as.w)
+			return
+		}
+		if len(report.Paths) == 0 {
+			dialog.ShowInformation("Reset "+cp.Name, "Nothing to delete.", mui.as.w)
+			return
+		}
+		mui.confirmChainReset(cp, opts, report.Paths)
+	}, mui.as.w)
+}
+
+// confirmChainReset shows the files a reset deletes and runs it.
+func (mui *MainUI) confirmChainReset(cp ChainProvider, opts ResetOptions, paths []string) {
+	msg := "These files will be deleted:"
+	if mui.isRunning(cp.ID) {
+		msg = fmt.Sprintf("%s is stopped first, then these files are deleted:", cp.Name)
+	}
+	files := widget.NewLabel(strings.Join(paths, "\n"))
+	scroll := container.NewScroll(files)
+	scroll.SetMinSize(fyne.NewSize(440, 160))
+
+	dialog.ShowCustomConfirm("Reset "+cp.Name, "Reset", "Cancel", container.NewVBox(widget.NewLabel(msg), scroll), func(ok bool) {
+		if !ok {
+			return
+		}
+		pu := widget.NewModalPopUp(widget.NewLabel(fmt.Sprintf("Resetting %s...", cp.Name)), mui.as.w.Canvas())
+		pu.Show()
+		go func() {
+			opts.DryRun = false
+			report, err := mui.ctl.ResetChain(cp.ID, opts)
+			pu.Hide()
+			switch {
+			case err != nil:
+				dialog.ShowError(fmt.Errorf("could not reset %s: %w", cp.Name, err), mui.as.w)
+			case report.Backup != "":
+				dialog.ShowInformation("Reset "+cp.Name, "The deleted files were backed up to\n"+report.Backup, mui.as.w)
+			}
+			mui.Refresh()
+		}()
+	}, mui.as.w)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+type RestartMode string
+
+const (
+	RestartNever     RestartMode = "never"
+	RestartOnFailure RestartMode = "on-failure"
+	RestartAlways    RestartMode = "always"
+)
+
+const (
+	defaultMaxRetries     = 5
+	defaultRestartBackoff = 2 * time.Second
+	maxRestartBackoff     = 5 * time.Minute
+	// A chain that ran at least this long before dying gets a fresh set of
+	// retries
+	restartStableAfter = 5 * time.Minute
+)
+
+type RestartPolicy struct {
+	Mode       RestartMode `json:"mode,omitempty"`
+	MaxRetries int         `json:"maxRetries,omitempty"` // Consecutive restarts before giving up, defaults to defaultMaxRetries
+	Backoff    int         `json:"backoff,omitempty"`    // Seconds before the first restart, doubled on every retry
+}
+
+// ShouldRestart reports whether a process that exited with status should be
+// relaunched. expected is true if the exit was requested by the launcher.
+// An adopted process whose exit code is unknown is not taken for a failure.
+func (rp RestartPolicy) ShouldRestart(status ProcessStatus, expected bool) bool {
+	if expected {
+		return false
+	}
+	switch rp.Mode {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return !status.ExitUnknown && status.ExitCode != 0
+	}
+	return false
+}
+
+func (rp RestartPolicy) maxRetries() int {
+	if rp.MaxRetries > 0 {
+		return rp.MaxRetries
+	}
+	return defaultMaxRetries
+}
+
+// Delay returns how long to wait before the given restart attempt, starting
+// at 1.
+func (rp RestartPolicy) Delay(attempt int) time.Duration {
+	d := defaultRestartBackoff
+	if rp.Backoff > 0 {
+		d = time.Duration(rp.Backoff) * time.Second
+	}
+	for i := 1; i < attempt && d < maxRestartBackoff; i++ {
+		d *= 2
+	}
+	if d > maxRestartBackoff {
+		d = maxRestartBackoff
+	}
+	return d
+}
+
+// scheduleRestart applies the chain's restart policy after an exit. It
+// updates the crash bookkeeping in cs and returns the delay until the
+// relaunch, or false if the chain should stay down.
+func scheduleRestart(cd *ChainData, cs *ChainState, status ProcessStatus, expected bool) (time.Duration, bool) {
+	if !status.ExitUnknown {
+		cs.LastExitCode = status.ExitCode
+	}
+	if expected {
+		cs.RestartAttempts = 0
+		return 0, false
+	}
+
+	if !status.ExitUnknown {
+		cs.CrashCount++
+	}
+	if status.ExitedAt.Sub(status.StartedAt) >= restartStableAfter {
+		cs.RestartAttempts = 0
+	}
+
+	if !cd.Restart.ShouldRestart(status, expected) {
+		return 0, false
+	}
+	if cs.RestartAttempts >= cd.Restart.maxRetries() {
+		println(fmt.Sprintf("%s crashed %d times in a row, not restarting", cd.ID, cs.RestartAttempts+1))
+		return 0, false
+	}
+	cs.RestartAttempts++
+	return cd.Restart.Delay(cs.RestartAttempts), true
+}
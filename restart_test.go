@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRestartPolicyDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RestartPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{"default first", RestartPolicy{}, 1, defaultRestartBackoff},
+		{"default doubles", RestartPolicy{}, 3, 4 * defaultRestartBackoff},
+		{"custom backoff", RestartPolicy{Backoff: 10}, 1, 10 * time.Second},
+		{"custom doubles", RestartPolicy{Backoff: 10}, 2, 20 * time.Second},
+		{"capped", RestartPolicy{}, 30, maxRestartBackoff},
+		{"backoff above cap", RestartPolicy{Backoff: 3600}, 1, maxRestartBackoff},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Delay(tt.attempt); got != tt.want {
+				t.Errorf("Delay(%d) = %v, want %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestartPolicyShouldRestart(t *testing.T) {
+	tests := []struct {
+		mode     RestartMode
+		exitCode int
+		unknown  bool
+		expected bool
+		want     bool
+	}{
+		{RestartNever, 1, false, false, false},
+		{RestartOnFailure, 1, false, false, true},
+		{RestartOnFailure, 0, false, false, false},
+		{RestartAlways, 0, false, false, true},
+		{RestartAlways, 1, false, true, false},
+		{"", 1, false, false, false},
+		{RestartOnFailure, -1, true, false, false},
+		{RestartAlways, -1, true, false, true},
+	}
+	for _, tt := range tests {
+		rp := RestartPolicy{Mode: tt.mode}
+		status := ProcessStatus{ExitCode: tt.exitCode, ExitUnknown: tt.unknown}
+		if got := rp.ShouldRestart(status, tt.expected); got != tt.want {
+			t.Errorf("%q.ShouldRestart(exit %d, expected %v) = %v, want %v", tt.mode, tt.exitCode, tt.expected, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleRestartGivesUp(t *testing.T) {
+	cd := &ChainData{ID: "testchain", Restart: RestartPolicy{Mode: RestartAlways, MaxRetries: 2}}
+	cs := &ChainState{}
+	start := time.Now()
+	crash := ProcessStatus{ExitCode: 1, StartedAt: start, ExitedAt: start.Add(time.Second)}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		delay, ok := scheduleRestart(cd, cs, crash, false)
+		if !ok || delay != cd.Restart.Delay(attempt) {
+			t.Fatalf("attempt %d: scheduleRestart = %v, %v", attempt, delay, ok)
+		}
+	}
+	if _, ok := scheduleRestart(cd, cs, crash, false); ok {
+		t.Fatal("restarted after MaxRetries crashes in a row")
+	}
+	if cs.CrashCount != 3 || cs.LastExitCode != 1 {
+		t.Errorf("CrashCount = %d, LastExitCode = %d, want 3 and 1", cs.CrashCount, cs.LastExitCode)
+	}
+
+	// A chain that ran long enough gets its retries back
+	stable := ProcessStatus{ExitCode: 1, StartedAt: start, ExitedAt: start.Add(restartStableAfter)}
+	if _, ok := scheduleRestart(cd, cs, stable, false); !ok {
+		t.Error("not restarted after running stable")
+	}
+}
+
+func TestScheduleRestartAfterUnknownExit(t *testing.T) {
+	cd := &ChainData{ID: "testchain", Restart: RestartPolicy{Mode: RestartOnFailure}}
+	cs := &ChainState{LastExitCode: 1}
+	start := time.Now()
+	adopted := ProcessStatus{ExitCode: -1, ExitUnknown: true, StartedAt: start, ExitedAt: start.Add(time.Second)}
+
+	if _, ok := scheduleRestart(cd, cs, adopted, false); ok {
+		t.Error("restarted an adopted process whose exit code is unknown")
+	}
+	if cs.CrashCount != 0 || cs.LastExitCode != 1 {
+		t.Errorf("CrashCount = %d, LastExitCode = %d, want 0 and 1", cs.CrashCount, cs.LastExitCode)
+	}
+}
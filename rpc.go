@@ -1,47 +1,10 @@
 package main
 
 import (
-	"bytes"
-	"encoding/base64"
-	"encoding/json"
-	"net/http"
-	"strconv"
-)
-
-type RPCRequest struct {
-	JSONRpc string        `json:"jsonrpc"`
-	ID      string        `json:"id"`
-	Method  string        `json:"method"`
-	Params  []interface{} `json:"params"`
-}
-
-type RPCGetBlockCountResponse struct {
-	Result int `json:"result"`
-}
-
-type RPCGetDepositAddressResponse struct {
-	Result string `json:"result"`
-}
-
-type RPCGetBalanceResponse struct {
-	Result float64 `json:"result"`
-}
+	"context"
 
-type RPCGetUnconfirmedBalanceResponse struct {
-	Result float64 `json:"result"`
-}
-
-type RPCRefreshBMMResponse struct {
-	Result RefreshBMMResult `json:"result"`
-	Error  interface{}      `json:"error"`
-	ID     string           `json:"id"`
-}
-
-type RPCCreateSidechainProposalResponse struct {
-	Result []ActiveSidechain `json:"result"`
-	Error  interface{}       `json:"error"`
-	ID     string            `json:"id"`
-}
+	"dc-launcher/rpc"
+)
 
 type ActiveSidechain struct {
 	Title       string `json:"title"`
@@ -62,29 +25,24 @@ type RefreshBMMResult struct {
 	Error                  string `json:"error"`
 }
 
-func MakeRpcRequest(chainData *ChainData, method string, params []interface{}) (*http.Response, error) {
-	auth := chainData.RPCUser + ":" + chainData.RPCPass
-	authBytes := []byte(auth)
-	authEncoded := base64.StdEncoding.EncodeToString(authBytes)
-	rpcRequest := RPCRequest{JSONRpc: "2.0", ID: "", Method: method, Params: params}
-	body, err := json.Marshal(rpcRequest)
-	if err != nil {
-		return nil, err
-	}
-	client := &http.Client{}
-	req, err := http.NewRequest("POST", "http://localhost:"+strconv.Itoa(chainData.Port), bytes.NewBuffer(body))
-	if err != nil {
-		return nil, err
+// RPCClient returns a JSON-RPC client for the chain. Clients share the
+// underlying http.Client so they are cheap to create.
+func (cd *ChainData) RPCClient() *rpc.Client {
+	c := rpc.NewClient(cd.RPCHost, cd.Port, cd.RPCUser, cd.RPCPass)
+	c.CookieFile = cd.CookieFile
+	return c
+}
+
+// Credentials returns the RPC user and password of the chain, read from its
+// cookie file if it uses cookie authentication. The cookie only exists while
+// the chain runs.
+func (cd *ChainData) Credentials() (string, string, error) {
+	if cd.RPCPass == "" && cd.CookieFile != "" {
+		return rpc.ReadCookie(cd.CookieFile)
 	}
-	req.Header.Add("Authorization", "Basic "+authEncoded)
-	req.Header.Add("content-type", "application/json")
-	return client.Do(req)
+	return cd.RPCUser, cd.RPCPass, nil
 }
 
-func PrintNonSuccessRPCResponse(r *http.Response) {
-	println("RPC Error: " + r.Status)
-	println("Response:")
-	buf := new(bytes.Buffer)
-	buf.ReadFrom(r.Body)
-	println(buf.String())
+func rpcContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), rpc.DefaultTimeout)
 }
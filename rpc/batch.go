@@ -0,0 +1,86 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// BatchCall is one request in a batch. Result should be a pointer the
+// result is decoded into, Err is set if that single call failed.
+type BatchCall struct {
+	Method string
+	Params []interface{}
+	Result interface{}
+	Err    error
+}
+
+// NewBatchCall returns a call decoding its result into out.
+func NewBatchCall(out interface{}, method string, params ...interface{}) *BatchCall {
+	if params == nil {
+		params = []interface{}{}
+	}
+	return &BatchCall{Method: method, Params: params, Result: out}
+}
+
+// Batch sends all calls in a single JSON-RPC batch request. The returned
+// error is only set if the batch as a whole failed, errors of individual
+// calls are stored in their Err field.
+func (c *Client) Batch(ctx context.Context, calls []*BatchCall) error {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	reqs := make([]Request, len(calls))
+	byID := make(map[uint64]*BatchCall, len(calls))
+	for i, call := range calls {
+		id := atomic.AddUint64(&c.nextID, 1)
+		reqs[i] = Request{JSONRpc: "2.0", ID: id, Method: call.Method, Params: call.Params}
+		byID[id] = call
+		call.Err = nil
+	}
+
+	body, status, err := c.post(ctx, reqs)
+	if err != nil {
+		return fmt.Errorf("rpc batch: %w", err)
+	}
+
+	var responses []Response
+	if err := json.Unmarshal(body, &responses); err != nil {
+		// A node rejecting the whole batch answers with a single object
+		var res Response
+		if json.Unmarshal(body, &res) == nil && res.Error != nil {
+			return res.Error
+		}
+		if status.code != http.StatusOK {
+			return &HTTPError{StatusCode: status.code, Status: status.text, Body: string(bytes.TrimSpace(body)), Method: "batch"}
+		}
+		return fmt.Errorf("rpc batch: decoding response: %w", err)
+	}
+
+	for _, res := range responses {
+		call, ok := byID[res.ID]
+		if !ok {
+			continue
+		}
+		delete(byID, res.ID)
+		if res.Error != nil {
+			res.Error.Method = call.Method
+			call.Err = res.Error
+			continue
+		}
+		if call.Result == nil || len(res.Result) == 0 || string(res.Result) == "null" {
+			continue
+		}
+		if err := json.Unmarshal(res.Result, call.Result); err != nil {
+			call.Err = fmt.Errorf("rpc %s: decoding result: %w", call.Method, err)
+		}
+	}
+	for _, call := range byID {
+		call.Err = fmt.Errorf("rpc %s: no response in batch", call.Method)
+	}
+	return nil
+}
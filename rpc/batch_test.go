@@ -0,0 +1,130 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+// testClient returns a client for a server running handler.
+func testClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(u.Port())
+	return NewClient(u.Hostname(), port, "user", "pass")
+}
+
+func TestBatchMatchesResponsesByID(t *testing.T) {
+	tests := []struct {
+		method  string
+		reply   func(id uint64) *Response
+		want    string
+		wantErr bool
+	}{
+		{
+			method: "getblockcount",
+			reply:  func(id uint64) *Response { return &Response{ID: id, Result: json.RawMessage(`42`)} },
+			want:   `42`,
+		},
+		{
+			method: "getbestblockhash",
+			reply:  func(id uint64) *Response { return &Response{ID: id, Result: json.RawMessage(`"00ff"`)} },
+			want:   `"00ff"`,
+		},
+		{
+			method: "getbalance",
+			reply: func(id uint64) *Response {
+				return &Response{ID: id, Error: &Error{Code: ErrCodeWalletNotFound, Message: "no wallet"}}
+			},
+			wantErr: true,
+		},
+		{
+			method:  "getmempoolinfo",
+			reply:   func(id uint64) *Response { return &Response{ID: id + 1000, Result: json.RawMessage(`{}`)} },
+			wantErr: true,
+		},
+		{
+			method:  "getnetworkinfo",
+			reply:   func(id uint64) *Response { return nil },
+			wantErr: true,
+		},
+	}
+
+	replies := make(map[string]func(uint64) *Response)
+	for _, tt := range tests {
+		replies[tt.method] = tt.reply
+	}
+	// Responses come back in reverse order, they must be matched by id
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var reqs []Request
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		out := []Response{}
+		for i := len(reqs) - 1; i >= 0; i-- {
+			if res := replies[reqs[i].Method](reqs[i].ID); res != nil {
+				out = append(out, *res)
+			}
+		}
+		json.NewEncoder(w).Encode(out)
+	})
+
+	results := make([]json.RawMessage, len(tests))
+	calls := make([]*BatchCall, len(tests))
+	for i, tt := range tests {
+		calls[i] = NewBatchCall(&results[i], tt.method)
+	}
+	if err := c.Batch(context.Background(), calls); err != nil {
+		t.Fatalf("Batch: %v", err)
+	}
+	for i, tt := range tests {
+		if tt.wantErr {
+			if calls[i].Err == nil {
+				t.Errorf("%s: no error, got result %s", tt.method, results[i])
+			}
+			continue
+		}
+		if calls[i].Err != nil || string(results[i]) != tt.want {
+			t.Errorf("%s = %s, %v, want %s", tt.method, results[i], calls[i].Err, tt.want)
+		}
+	}
+	if !IsCode(calls[2].Err, ErrCodeWalletNotFound) {
+		t.Errorf("getbalance error = %v, want code %d", calls[2].Err, ErrCodeWalletNotFound)
+	}
+}
+
+func TestBatchRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"unauthorized", http.StatusUnauthorized, ""},
+		{"server error", http.StatusInternalServerError, "overloaded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+			var height int
+			err := c.Batch(context.Background(), []*BatchCall{NewBatchCall(&height, "getblockcount")})
+			var httpErr *HTTPError
+			if !errors.As(err, &httpErr) || httpErr.StatusCode != tt.status {
+				t.Fatalf("Batch error = %v, want HTTP status %d", err, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+// Package rpc is a small JSON-RPC client for bitcoind style nodes.
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultHost    = "127.0.0.1"
+	DefaultTimeout = 10 * time.Second
+)
+
+// Shared between all clients so connections to the nodes are reused
+var defaultHTTPClient = &http.Client{
+	Transport: &http.Transport{
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     90 * time.Second,
+	},
+}
+
+type Client struct {
+	URL  string
+	User string
+	Pass string
+	// Read for every request if Pass is empty, bitcoind writes a new cookie
+	// each time it starts
+	CookieFile string
+	Timeout    time.Duration
+	HTTP       *http.Client
+
+	nextID uint64
+}
+
+// NewClient returns a client for the node listening on host:port. An empty
+// host defaults to DefaultHost.
+func NewClient(host string, port int, user string, pass string) *Client {
+	if host == "" {
+		host = DefaultHost
+	}
+	return &Client{
+		URL:     "http://" + net.JoinHostPort(host, strconv.Itoa(port)),
+		User:    user,
+		Pass:    pass,
+		Timeout: DefaultTimeout,
+		HTTP:    defaultHTTPClient,
+	}
+}
+
+type Request struct {
+	JSONRpc string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type Response struct {
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+	ID     uint64          `json:"id"`
+}
+
+// Error is the error object returned by the node, Code is the bitcoind
+// RPC error code (see bitcoin/src/rpc/protocol.h).
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Method  string `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Method != "" {
+		return fmt.Sprintf("rpc %s: %s (code %d)", e.Method, e.Message, e.Code)
+	}
+	return fmt.Sprintf("rpc: %s (code %d)", e.Message, e.Code)
+}
+
+// HTTPError is returned when the node answers with a non 200 status and no
+// JSON-RPC error object, e.g. 401 on bad credentials.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Body       string
+	Method     string
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("rpc %s: http %s", e.Method, e.Status)
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// Common bitcoind error codes
+const (
+	ErrCodeMiscError          = -1
+	ErrCodeTypeError          = -3
+	ErrCodeInvalidAddress     = -5
+	ErrCodeInvalidParameter   = -8
+	ErrCodeInWarmup           = -28
+	ErrCodeMethodNotFound     = -32601
+	ErrCodeWalletError        = -4
+	ErrCodeWalletNotFound     = -18
+	ErrCodeWalletNotSpecified = -19
+)
+
+// IsCode reports whether err is an rpc *Error with the given code.
+func IsCode(err error, code int) bool {
+	var rpcErr *Error
+	return errors.As(err, &rpcErr) && rpcErr.Code == code
+}
+
+// Do sends a single request and returns the raw result.
+func (c *Client) Do(ctx context.Context, method string, params ...interface{}) (json.RawMessage, error) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	req := Request{JSONRpc: "2.0", ID: atomic.AddUint64(&c.nextID, 1), Method: method, Params: params}
+
+	body, status, err := c.post(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("rpc %s: %w", method, err)
+	}
+
+	var res Response
+	if err := json.Unmarshal(body, &res); err != nil {
+		if status.code != http.StatusOK {
+			return nil, &HTTPError{StatusCode: status.code, Status: status.text, Body: string(bytes.TrimSpace(body)), Method: method}
+		}
+		return nil, fmt.Errorf("rpc %s: decoding response: %w", method, err)
+	}
+	if res.Error != nil {
+		res.Error.Method = method
+		return nil, res.Error
+	}
+	if status.code != http.StatusOK {
+		return nil, &HTTPError{StatusCode: status.code, Status: status.text, Method: method}
+	}
+	return res.Result, nil
+}
+
+type httpStatus struct {
+	code int
+	text string
+}
+
+func (c *Client) post(ctx context.Context, payload interface{}) ([]byte, httpStatus, error) {
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, httpStatus{}, err
+	}
+
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, httpStatus{}, err
+	}
+	user, pass := c.User, c.Pass
+	if pass == "" && c.CookieFile != "" {
+		if user, pass, err = ReadCookie(c.CookieFile); err != nil {
+			return nil, httpStatus{}, err
+		}
+	}
+	req.SetBasicAuth(user, pass)
+	req.Header.Set("Content-Type", "application/json")
+
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = defaultHTTPClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, httpStatus{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, httpStatus{}, err
+	}
+	return body, httpStatus{code: resp.StatusCode, text: resp.Status}, nil
+}
+
+// ReadCookie reads the user and password from a bitcoind .cookie file.
+func ReadCookie(path string) (string, string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("reading auth cookie: %w", err)
+	}
+	user, pass, ok := strings.Cut(strings.TrimSpace(string(b)), ":")
+	if !ok {
+		return "", "", fmt.Errorf("%s is not an auth cookie", path)
+	}
+	return user, pass, nil
+}
+
+// Call sends a request and decodes the result into T.
+func Call[T any](ctx context.Context, c *Client, method string, params ...interface{}) (T, error) {
+	var out T
+	raw, err := c.Do(ctx, method, params...)
+	if err != nil {
+		return out, err
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return out, nil
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return out, fmt.Errorf("rpc %s: decoding result: %w", method, err)
+	}
+	return out, nil
+}
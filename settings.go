@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+const launcherSettingsName = "launcher.json"
+
+// LauncherSettings are the launcher wide settings in
+// ~/.dclauncher/launcher.json, one per profile. A missing file means all
+// defaults.
+type LauncherSettings struct {
+	// Chain catalogue to fetch, nothing is fetched if empty
+	CatalogueURL string `json:"catalogueUrl,omitempty"`
+	// Base64 ed25519 public key an unofficial catalogue is signed with
+	CatalogueKey string `json:"catalogueKey,omitempty"`
+	// Trust CatalogueKey instead of the release key, for catalogues not
+	// published by the project
+	UnofficialCatalogue bool `json:"unofficialCatalogue,omitempty"`
+	// "warn" starts chains whose installed files were modified, by default
+	// they are refused
+	VerifyBinaries string `json:"verifyBinaries,omitempty"`
+	// regtest, signet or testnet, regtest if empty
+	Network string `json:"network,omitempty"`
+	// Added to the catalogue's default ports, set for named profiles so
+	// they don't clash with other profiles
+	PortOffset int `json:"portOffset,omitempty"`
+}
+
+// launcherDir is the launcher directory of the profile of this process.
+func launcherDir() (string, error) {
+	root, err := profileHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, ".dclauncher"), nil
+}
+
+func LoadSettings() (LauncherSettings, error) {
+	dir, err := launcherDir()
+	if err != nil {
+		return LauncherSettings{}, err
+	}
+	return readSettings(dir)
+}
+
+func readSettings(dir string) (LauncherSettings, error) {
+	var s LauncherSettings
+	b, err := os.ReadFile(filepath.Join(dir, launcherSettingsName))
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal(b, &s)
+	return s, err
+}
+
+// SaveLauncherSettings writes s to launcher.json.
+func SaveLauncherSettings(s LauncherSettings) error {
+	dir, err := launcherDir()
+	if err != nil {
+		return err
+	}
+	return writeSettings(dir, s)
+}
+
+func writeSettings(dir string, s LauncherSettings) error {
+	b, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, launcherSettingsName), append(b, '\n'), 0o644)
+}
+
+// loadLauncherSettings reads the launcher network and the profile's port
+// offset from launcher.json.
+func (as *AppState) loadLauncherSettings() error {
+	s, err := LoadSettings()
+	if err != nil {
+		println(err.Error())
+	}
+	as.portOffset = s.PortOffset
+	as.network, err = ParseNetwork(s.Network)
+	return err
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// NewSettingsButton returns the row button opening ShowChainSettings.
+func (mui *MainUI) NewSettingsButton(cp ChainProvider, notice *widget.RichText) *widget.Button {
+	b := widget.NewButtonWithIcon("", theme.SettingsIcon(), func() {
+		mui.ShowChainSettings(cp, notice)
+	})
+	b.Importance = widget.LowImportance
+	return b
+}
+
+// splitDebug splits the debug categories typed into the settings dialog.
+func splitDebug(v string) []string {
+	return strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+// ShowChainSettings lets the user edit the conf file of a chain and offers
+// to restart it if it is running.
+func (mui *MainUI) ShowChainSettings(cp ChainProvider, notice *widget.RichText) {
+	s, err := mui.ctl.Settings(cp.ID)
+	if err != nil {
+		dialog.ShowError(err, mui.as.w)
+		return
+	}
+	kind, drv, err := DriverFor(cp)
+	if err != nil {
+		dialog.ShowError(err, mui.as.w)
+		return
+	}
+
+	port := widget.NewEntry()
+	port.SetText(strconv.Itoa(s.RPCPort))
+	port.Validator = validatePort
+	st := mui.chainStatus(cp.ID)
+	items := []*widget.FormItem{
+		widget.NewFormItem("Network", widget.NewLabel(s.Network)),
+		widget.NewFormItem("RPC port", port),
+		widget.NewFormItem("", inUseLabel(st.RPCPort, st.RPCPortSource)),
+	}
+
+	user := widget.NewEntry()
+	user.SetText(s.RPCUser)
+	user.Validator = validateRPCUser
+	pass := widget.NewPasswordEntry()
+	pass.SetText(s.RPCPassword)
+	pass.Validator = validateRPCPassword
+	user.SetPlaceHolder("Empty for cookie authentication")
+	pass.SetPlaceHolder("Empty for cookie authentication")
+	dataDir := widget.NewEntry()
+	dataDir.SetText(s.DataDir)
+	dataDir.Validator = validateDataDir
+	debug := widget.NewEntry()
+	debug.SetText(strings.Join(s.Debug, ", "))
+	debug.SetPlaceHolder("e.g. net, rpc or 1 for everything")
+	debug.Validator = func(v string) error {
+		return validateDebug(splitDebug(v))
+	}
+	if drv.SupportsRPC() {
+		items = append(items,
+			widget.NewFormItem("RPC user", user),
+			widget.NewFormItem("RPC password", pass),
+			widget.NewFormItem("Data directory", dataDir),
+			widget.NewFormItem("Debug", debug),
+		)
+	}
+
+	slot := widget.NewEntry()
+	slot.SetText(strconv.Itoa(s.Slot))
+	slot.Validator = validateSlot
+	refreshBMM := widget.NewCheck("Refresh BMM", nil)
+	refreshBMM.SetChecked(s.RefreshBMM)
+	bmmFee := widget.NewCheck("Pay BMM fee", nil)
+	bmmFee.SetChecked(s.BMMFee)
+	if kind != KindDrivechain {
+		items = append(items,
+			widget.NewFormItem("Slot", slot),
+			widget.NewFormItem("", inUseLabel(st.Slot, st.SlotSource)),
+			widget.NewFormItem("", refreshBMM),
+			widget.NewFormItem("", bmmFee),
+		)
+	}
+
+	d := dialog.NewForm(cp.Name+" settings", "Save", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		// The form only submits once every validator passed
+		s.RPCPort, _ = strconv.Atoi(port.Text)
+		s.RPCUser = user.Text
+		s.RPCPassword = pass.Text
+		s.DataDir = dataDir.Text
+		s.Debug = splitDebug(debug.Text)
+		s.Slot, _ = strconv.Atoi(slot.Text)
+		s.RefreshBMM = refreshBMM.Checked
+		s.BMMFee = bmmFee.Checked
+		if err := mui.ctl.SaveSettings(cp.ID, s); err != nil {
+			dialog.ShowError(err, mui.as.w)
+			return
+		}
+		if !mui.isRunning(cp.ID) {
+			return
+		}
+		msg := fmt.Sprintf("The new settings apply once %s is restarted. Restart it now?", cp.Name)
+		dialog.ShowConfirm("Restart "+cp.Name, msg, func(restart bool) {
+			if restart {
+				mui.RestartChainWithProgress(cp.Name, cp.ID, notice)
+			}
+		}, mui.as.w)
+	}, mui.as.w)
+	d.Resize(fyne.NewSize(460, d.MinSize().Height))
+	d.Show()
+}
+
+// inUseLabel shows the value the launcher uses and where it comes from, a
+// command line flag beats the conf file.
+func inUseLabel(v int, source ValueSource) *widget.Label {
+	l := widget.NewLabel("")
+	if source != "" {
+		l.SetText(fmt.Sprintf("In use: %d (%s)", v, source))
+	}
+	l.TextStyle = fyne.TextStyle{Italic: true}
+	return l
+}
+
+func (mui *MainUI) isRunning(id string) bool {
+	if id == mui.drivechainID {
+		return mui.as.drivechainState().State != Unknown
+	}
+	return mui.sidechainState(id).State != Unknown
+}
+
+// RestartChainWithProgress stops a chain and starts it again, e.g. to apply
+// new settings.
+func (mui *MainUI) RestartChainWithProgress(name string, id string, notice *widget.RichText) {
+	lbl := widget.NewLabel(fmt.Sprintf("Restarting %s...", name))
+	pu := widget.NewModalPopUp(lbl, mui.as.w.Canvas())
+	pu.Show()
+	go func() {
+		err := mui.ctl.Stop(id, func(step string) {
+			lbl.SetText(step)
+		})
+		if err == nil {
+			lbl.SetText(fmt.Sprintf("Launching %s...", name))
+			err = mui.ctl.Start(id)
+		}
+		pu.Hide()
+		ShowLaunchError(notice, err)
+		mui.offerFreePorts(name, id, notice, err)
+		mui.Refresh()
+	}()
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"sync"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 )
@@ -13,20 +15,202 @@ type AppState struct {
 	dcs ChainState
 	scd map[string]ChainData
 	scs map[string]ChainState
+	mu  sync.Mutex // Guards dcd, dcs, scd and scs, written by the per chain pollers, the supervisor and the controls, and downloads
 	cp  map[string]ChainProvider
+	sup *Supervisor
+
+	// Artifact downloads in progress by chain id
+	downloads map[string]*DownloadProgress
+
+	// Called whenever chain state changed, set by the UI
+	refresh func()
+	// Chains are started to outlive this process, their output goes to log
+	// files instead of our stdout
+	detach bool
+	// Network chains are switched to when they start
+	network Network
+	// Added to default P2P ports, the catalogue's ports already include it
+	portOffset int
+	// RPC ports and slots by chain id given on the command line
+	flagPorts map[string]int
+	flagSlots map[string]int
+}
+
+// NewAppState returns the launcher core state. It does not touch Fyne so it
+// can be used headless, call InitUI before building the UI.
+func NewAppState() *AppState {
+	as := &AppState{
+		scd:       make(map[string]ChainData),
+		scs:       make(map[string]ChainState),
+		sup:       NewSupervisor(),
+		downloads: make(map[string]*DownloadProgress),
+	}
+	as.sup.OnExit = func(status ProcessStatus, expected bool) {
+		ChainExited(as, status, expected)
+	}
+	return as
 }
 
-func NewAppState(id string, title string) *AppState {
-	a := app.NewWithID(id)
-	w := a.NewWindow(title)
+func (as *AppState) InitUI(id string, title string) {
+	as.a = app.NewWithID(id)
+	as.w = as.a.NewWindow(title)
 	t := NewCustomTheme()
-	a.Settings().SetTheme(t)
+	as.a.Settings().SetTheme(t)
+	as.t = *t
+}
+
+// Refresh notifies the UI, if any, that chain state changed.
+func (as *AppState) Refresh() {
+	if as.refresh != nil {
+		as.refresh()
+	}
+}
+
+// drivechainData returns a copy of the data of the drivechain.
+func (as *AppState) drivechainData() ChainData {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	return as.dcd
+}
+
+// drivechainState returns a copy of the state of the drivechain.
+func (as *AppState) drivechainState() ChainState {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	return as.dcs
+}
+
+func (as *AppState) setDrivechainState(cs ChainState) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.dcs = cs
+}
+
+// sidechains returns a copy of the data of every sidechain.
+func (as *AppState) sidechains() []ChainData {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	chains := make([]ChainData, 0, len(as.scd))
+	for _, cd := range as.scd {
+		chains = append(chains, cd)
+	}
+	return chains
+}
+
+// chainData returns a copy of the data of chain id, the drivechain or a
+// sidechain.
+func (as *AppState) chainData(id string) (ChainData, bool) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	if id != "" && id == as.dcd.ID {
+		return as.dcd, true
+	}
+	cd, ok := as.scd[id]
+	return cd, ok
+}
+
+// chainState returns a copy of the state of chain id, the drivechain or a
+// sidechain.
+func (as *AppState) chainState(id string) (ChainState, bool) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	if id != "" && id == as.dcd.ID {
+		return as.dcs, true
+	}
+	cs, ok := as.scs[id]
+	return cs, ok
+}
+
+func (as *AppState) setChainState(id string, cs ChainState) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	if id != "" && id == as.dcd.ID {
+		as.dcs = cs
+		return
+	}
+	as.scs[id] = cs
+}
+
+// setPolledState stores what a poll of chain id found. Automining and the
+// restart bookkeeping may have changed while the poll ran, those are kept.
+func (as *AppState) setPolledState(id string, cs ChainState) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	keep := func(cur ChainState) ChainState {
+		cs.Automine = cur.Automine
+		cs.CrashCount, cs.LastExitCode, cs.RestartAttempts = cur.CrashCount, cur.LastExitCode, cur.RestartAttempts
+		return cs
+	}
+	if id != "" && id == as.dcd.ID {
+		as.dcs = keep(as.dcs)
+	} else if cur, ok := as.scs[id]; ok {
+		as.scs[id] = keep(cur)
+	}
+}
+
+// setAutomine turns mining a block on every poll of the drivechain on or
+// off.
+func (as *AppState) setAutomine(enabled bool) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.dcs.Automine = enabled
+}
+
+// pollerQuits returns the channels stopping the running pollers.
+func (as *AppState) pollerQuits() []chan struct{} {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	var quits []chan struct{}
+	if q := as.dcs.ChainStateUpdate.quit; q != nil {
+		quits = append(quits, q)
+	}
+	for _, cs := range as.scs {
+		if q := cs.ChainStateUpdate.quit; q != nil {
+			quits = append(quits, q)
+		}
+	}
+	return quits
+}
+
+// sidechainState returns a copy of the state of sidechain id.
+func (as *AppState) sidechainState(id string) (ChainState, bool) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	cs, ok := as.scs[id]
+	return cs, ok
+}
+
+func (as *AppState) setSidechainState(id string, cs ChainState) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.scs[id] = cs
+}
+
+// setChainData stores cd, e.g. after its conf or version changed.
+func (as *AppState) setChainData(cd *ChainData) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	if cd.IsDrivechain {
+		as.dcd = *cd
+		return
+	}
+	as.scd[cd.ID] = *cd
+}
+
+// download returns the progress of the running download for chain id, nil
+// if there is none.
+func (as *AppState) download(id string) *DownloadProgress {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	return as.downloads[id]
+}
 
-	return &AppState{
-		a:   a,
-		w:   w,
-		t:   *t,
-		scd: make(map[string]ChainData),
-		scs: make(map[string]ChainState),
+func (as *AppState) setDownload(id string, p *DownloadProgress) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	if p == nil {
+		delete(as.downloads, id)
+		return
 	}
+	as.downloads[id] = p
 }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+func testAppState() *AppState {
+	as := NewAppState()
+	as.setChainData(&ChainData{ID: "drivechain", IsDrivechain: true})
+	as.setChainData(&ChainData{ID: "testchain"})
+	as.setChainState("drivechain", ChainState{ID: "drivechain"})
+	as.setChainState("testchain", ChainState{ID: "testchain"})
+	return as
+}
+
+func TestSetPolledStateKeepsBookkeeping(t *testing.T) {
+	for _, id := range []string{"drivechain", "testchain"} {
+		as := testAppState()
+		polled, _ := as.chainState(id)
+
+		// Written while the poll ran
+		cs, _ := as.chainState(id)
+		cs.Automine, cs.CrashCount, cs.LastExitCode, cs.RestartAttempts = true, 2, 1, 1
+		as.setChainState(id, cs)
+
+		polled.State, polled.Height = Running, 10
+		as.setPolledState(id, polled)
+		got, _ := as.chainState(id)
+		want := ChainState{ID: id, State: Running, Height: 10, Automine: true, CrashCount: 2, LastExitCode: 1, RestartAttempts: 1}
+		if got != want {
+			t.Errorf("%s: got %+v, want %+v", id, got, want)
+		}
+	}
+	as := testAppState()
+	as.setPolledState("unknown", ChainState{ID: "unknown"})
+	if _, ok := as.chainState("unknown"); ok {
+		t.Error("state stored for an unknown chain")
+	}
+}
+
+func TestChainByIDReturnsCopies(t *testing.T) {
+	as := testAppState()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			as.setAutomine(true)
+			as.setPolledState("drivechain", ChainState{ID: "drivechain", State: Running})
+		}()
+		go func() {
+			defer wg.Done()
+			for _, id := range chainIDs(as) {
+				cd, cs, err := chainByID(as, id)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				cd.Port, cs.Height = 1, 1
+			}
+		}()
+	}
+	wg.Wait()
+	if cd, _ := as.chainData("drivechain"); cd.Port != 0 {
+		t.Error("chainByID handed out the shared drivechain data")
+	}
+	if cs := as.drivechainState(); cs.Height != 0 || !cs.Automine {
+		t.Errorf("drivechain state = %+v", cs)
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var ErrNotRunning = errors.New("process is not running")
+
+// killTimeout is how long Kill waits for a killed process to be reaped.
+const killTimeout = time.Minute
+
+// CommandBuilder returns a fresh, unstarted command. It is called again on
+// every (re)start since an exec.Cmd can only be started once.
+type CommandBuilder func() (*exec.Cmd, error)
+
+type ProcessStatus struct {
+	ID       string `json:"id"`
+	PID      int    `json:"pid,omitempty"`
+	Running  bool   `json:"running"`
+	ExitCode int    `json:"exitcode"`
+	ExitErr  string `json:"exiterr,omitempty"`
+	// The process was adopted, not our child, so its exit code is unknown
+	ExitUnknown bool      `json:"exitunknown,omitempty"`
+	StartedAt   time.Time `json:"startedat,omitempty"`
+	ExitedAt    time.Time `json:"exitedat,omitempty"`
+}
+
+type process struct {
+	id      string
+	build   CommandBuilder
+	cmd     *exec.Cmd // nil for adopted processes
+	proc    *os.Process
+	status  ProcessStatus
+	stopped bool // stop was requested, the exit is expected
+	done    chan struct{}
+}
+
+// Supervisor owns every chain process started by the launcher. It reaps
+// them with Wait as soon as they exit so they never linger as zombies.
+type Supervisor struct {
+	mu    sync.Mutex
+	procs map[string]*process
+
+	// OnExit is called from the reaping goroutine after a process exited
+	OnExit func(status ProcessStatus, expected bool)
+}
+
+func NewSupervisor() *Supervisor {
+	return &Supervisor{procs: make(map[string]*process)}
+}
+
+// Start builds and starts the command for id. It fails if a process for
+// id is already running.
+func (s *Supervisor) Start(id string, build CommandBuilder) (ProcessStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, ok := s.procs[id]; ok && p.status.Running {
+		return p.status, fmt.Errorf("%s is already running with pid %d", id, p.status.PID)
+	}
+
+	cmd, err := build()
+	if err != nil {
+		return ProcessStatus{ID: id}, err
+	}
+	if err := cmd.Start(); err != nil {
+		return ProcessStatus{ID: id}, err
+	}
+
+	p := &process{
+		id:    id,
+		build: build,
+		cmd:   cmd,
+		proc:  cmd.Process,
+		status: ProcessStatus{
+			ID:        id,
+			PID:       cmd.Process.Pid,
+			Running:   true,
+			StartedAt: time.Now(),
+		},
+		done: make(chan struct{}),
+	}
+	s.procs[id] = p
+	go s.reap(p)
+
+	println(fmt.Sprintf("%s started with pid %d", id, p.status.PID))
+	return p.status, nil
+}
+
+// Adopt tracks a process the launcher started in an earlier run, e.g.
+// found through a pid file. Adopted processes can be signalled and waited
+// on but their exit code is unknown.
+func (s *Supervisor) Adopt(id string, pid int) (ProcessStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, ok := s.procs[id]; ok && p.status.Running {
+		return p.status, fmt.Errorf("%s is already running with pid %d", id, p.status.PID)
+	}
+	if !pidAlive(pid) {
+		return ProcessStatus{ID: id}, ErrNotRunning
+	}
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return ProcessStatus{ID: id}, err
+	}
+
+	p := &process{
+		id:   id,
+		proc: proc,
+		status: ProcessStatus{
+			ID:        id,
+			PID:       pid,
+			Running:   true,
+			StartedAt: time.Now(),
+		},
+		done: make(chan struct{}),
+	}
+	s.procs[id] = p
+	go s.reap(p)
+	return p.status, nil
+}
+
+func pidAlive(pid int) bool {
+	return syscall.Kill(pid, 0) != syscall.ESRCH
+}
+
+func (s *Supervisor) reap(p *process) {
+	var (
+		err      error
+		exitCode = -1
+	)
+	if p.cmd != nil {
+		err = p.cmd.Wait()
+		exitCode = p.cmd.ProcessState.ExitCode()
+	} else {
+		// Not our child, we can only poll until it is gone
+		for pidAlive(p.status.PID) {
+			time.Sleep(500 * time.Millisecond)
+		}
+	}
+
+	s.mu.Lock()
+	p.status.Running = false
+	p.status.ExitedAt = time.Now()
+	p.status.ExitCode = exitCode
+	p.status.ExitUnknown = p.cmd == nil
+	if err != nil {
+		p.status.ExitErr = err.Error()
+	}
+	status := p.status
+	expected := p.stopped
+	onExit := s.OnExit
+	close(p.done)
+	s.mu.Unlock()
+
+	if status.ExitUnknown {
+		println(fmt.Sprintf("%s (pid %d) exited", status.ID, status.PID))
+	} else {
+		println(fmt.Sprintf("%s (pid %d) exited with code %d", status.ID, status.PID, status.ExitCode))
+	}
+	if onExit != nil {
+		onExit(status, expected)
+	}
+}
+
+// ExpectExit marks the running process for id as being stopped on purpose,
+// e.g. before asking it to shut down over RPC.
+func (s *Supervisor) ExpectExit(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.procs[id]; ok && p.status.Running {
+		p.stopped = true
+	}
+}
+
+// Signal sends sig to the process group of id. Chains are started in their
+// own process group so helpers spawned by e.g. the bitnames start.sh get the
+// signal too.
+func (s *Supervisor) Signal(id string, sig syscall.Signal) error {
+	s.mu.Lock()
+	p, ok := s.procs[id]
+	if !ok || !p.status.Running {
+		s.mu.Unlock()
+		return ErrNotRunning
+	}
+	p.stopped = true
+	pid := p.status.PID
+	s.mu.Unlock()
+
+	if err := syscall.Kill(-pid, sig); err != nil {
+		// Not a group leader, signal the process itself
+		return p.proc.Signal(sig)
+	}
+	return nil
+}
+
+// Kill sends SIGKILL to the process group of id and waits for it to be
+// reaped.
+func (s *Supervisor) Kill(id string) error {
+	if err := s.Signal(id, syscall.SIGKILL); err != nil {
+		return err
+	}
+	if !s.Wait(id, killTimeout) {
+		return fmt.Errorf("%s did not exit %s after SIGKILL", id, killTimeout)
+	}
+	return nil
+}
+
+// Terminate sends SIGTERM and escalates to SIGKILL if the process has not
+// exited after timeout.
+func (s *Supervisor) Terminate(id string, timeout time.Duration) error {
+	if err := s.Signal(id, syscall.SIGTERM); err != nil {
+		return err
+	}
+	if s.Wait(id, timeout) {
+		return nil
+	}
+	return s.Kill(id)
+}
+
+// Restart stops id if it is running and starts it again with the command
+// builder it was last started with.
+func (s *Supervisor) Restart(id string) (ProcessStatus, error) {
+	s.mu.Lock()
+	p, ok := s.procs[id]
+	s.mu.Unlock()
+	if !ok || p.build == nil {
+		return ProcessStatus{ID: id}, fmt.Errorf("%s was not started by the launcher", id)
+	}
+
+	if err := s.Terminate(id, defaultStopTimeout); err != nil && err != ErrNotRunning {
+		return s.Status(id), err
+	}
+	return s.Start(id, p.build)
+}
+
+// Status returns the state of the last process started for id.
+func (s *Supervisor) Status(id string) ProcessStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.procs[id]; ok {
+		return p.status
+	}
+	return ProcessStatus{ID: id}
+}
+
+func (s *Supervisor) IsRunning(id string) bool {
+	return s.Status(id).Running
+}
+
+// Wait blocks until the process for id has exited or the timeout passed.
+// Returns true if the process is no longer running.
+func (s *Supervisor) Wait(id string, timeout time.Duration) bool {
+	s.mu.Lock()
+	p, ok := s.procs[id]
+	s.mu.Unlock()
+	if !ok {
+		return true
+	}
+	select {
+	case <-p.done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strconv"
+	"sync"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -20,27 +22,39 @@ type MainUI struct {
 	contentContainer *fyne.Container
 	footerContainer  *fyne.Container
 	as               *AppState
+	ctl              Controller
+	drivechainID     string
 	driveChainRow    DrivechainRow
+	mu               sync.Mutex
+	statuses         map[string]ChainStatus
+	network          Network
+	networkLabel     *widget.Label
 	sideChainRows    []SidechainRow
+	// The rows of every chain, grouped by node if there are several
+	chainList *fyne.Container
 }
 
-func NewMainUI(as *AppState) *MainUI {
+func NewMainUI(as *AppState, ctl Controller) *MainUI {
 	mui := &MainUI{
 		headerContainer:  container.NewVBox(),
 		contentContainer: container.NewStack(),
 		footerContainer:  container.NewStack(),
 		as:               as,
+		ctl:              ctl,
 	}
 
 	menus := fyne.NewMainMenu(&fyne.Menu{
 		Label: "File",
 		Items: []*fyne.MenuItem{
+			{Label: "Network...", Action: func() {
+				mui.ShowNetwork()
+			}},
 			{Label: "Reset Everything", Action: func() {
 				dialog.NewConfirm("Reset Everything", "This will delete all data and settings for Drivechain and Sidechains.", func(b bool) {
 					if b {
 						pu := widget.NewModalPopUp(widget.NewLabel("Resetting, please wait..."), mui.as.w.Canvas())
 						pu.Show()
-						err := ResetEverything(mui.as)
+						err := mui.ctl.Reset()
 						if err != nil {
 							println(err.Error())
 						}
@@ -54,13 +68,20 @@ func NewMainUI(as *AppState) *MainUI {
 
 	as.w.SetMainMenu(menus)
 
+	mui.networkLabel = widget.NewLabel("")
+	mui.networkLabel.Alignment = fyne.TextAlignCenter
+	mui.networkLabel.TextStyle = fyne.TextStyle{Bold: true}
+	mui.headerContainer.Add(mui.networkLabel)
+
 	lv := container.NewVBox()
+	mui.chainList = lv
 
-	mui.driveChainRow = NewDrivechainRow(mui, mui.as.cp["drivechain"], lv)
+	mui.drivechainID = drivechainID(as.cp)
+	mui.driveChainRow = NewDrivechainRow(mui, mui.as.cp[mui.drivechainID], lv)
 
 	var orderedChainProviders []ChainProvider
 	for k, cp := range as.cp {
-		if k != "drivechain" {
+		if k != mui.drivechainID {
 			orderedChainProviders = append(orderedChainProviders, cp)
 		}
 	}
@@ -106,51 +127,348 @@ func NewMainUI(as *AppState) *MainUI {
 
 	as.w.SetContent(container.NewBorder(mui.headerContainer, mui.footerContainer, nil, nil, mui.contentContainer))
 	as.w.Resize(fyne.NewSize(540, 880))
+	as.refresh = mui.Refresh
+	_, remote := ctl.(*ControlClient)
+	go mui.watchStatus(remote)
 	return mui
 }
 
+// watchStatus keeps the installed versions shown by the rows and the ports
+// shown in the settings up to date. If
+// remote, the chain state kept by the launcher daemon is copied into the
+// local state too.
+func (mui *MainUI) watchStatus(remote bool) {
+	for range time.Tick(time.Second) {
+		statuses, err := mui.ctl.Status()
+		if err != nil {
+			println(err.Error())
+			continue
+		}
+		byID := make(map[string]ChainStatus)
+		for _, st := range statuses {
+			byID[st.ID] = st
+		}
+		n, err := mui.ctl.Network()
+		if err != nil {
+			println(err.Error())
+		}
+		mui.mu.Lock()
+		mui.statuses = byID
+		if err == nil {
+			mui.network = n
+		}
+		mui.mu.Unlock()
+		if !remote {
+			mui.Refresh()
+			continue
+		}
+		for _, st := range statuses {
+			mui.as.setDownload(st.ID, st.Download)
+			if st.ID == mui.drivechainID {
+				cs := mui.as.drivechainState()
+				applyChainStatus(&cs, st)
+				mui.as.setDrivechainState(cs)
+				continue
+			}
+			cs := mui.sidechainState(st.ID)
+			applyChainStatus(&cs, st)
+			mui.as.setSidechainState(st.ID, cs)
+		}
+		mui.Refresh()
+	}
+}
+
+func applyChainStatus(cs *ChainState, st ChainStatus) {
+	cs.State = st.State
+	cs.Height = st.Height
+	cs.AvailableBalance = st.AvailableBalance
+	cs.PendingBalance = st.PendingBalance
+	cs.MempoolSize = st.MempoolSize
+	cs.BestBlockHash = st.BestBlockHash
+	cs.Automine = st.Automine
+	cs.CrashCount = st.CrashCount
+	cs.LastExitCode = st.LastExitCode
+}
+
+// refreshNetwork shows the launcher network in the header, and the
+// drivechain's if it still runs on another one.
+func (mui *MainUI) refreshNetwork() {
+	mui.mu.Lock()
+	n := mui.network
+	mui.mu.Unlock()
+	if n == "" {
+		return
+	}
+	text := "Network: " + string(n)
+	if dn := mui.chainStatus(mui.drivechainID).Network; dn != "" && dn != n.Section() {
+		text += fmt.Sprintf(" (drivechain on %s until restarted)", dn)
+	}
+	mui.networkLabel.SetText(text)
+}
+
+// ShowNetwork lets the user switch the network all chains run on.
+func (mui *MainUI) ShowNetwork() {
+	var names []string
+	for _, n := range networks {
+		names = append(names, string(n))
+	}
+	mui.mu.Lock()
+	current := mui.network
+	mui.mu.Unlock()
+	rg := widget.NewRadioGroup(names, nil)
+	rg.SetSelected(string(current))
+	msg := widget.NewLabel("Stopped chains switch right away, running\nchains the next time they are started.")
+	dialog.ShowCustomConfirm("Network", "Switch", "Cancel", container.NewVBox(rg, msg), func(ok bool) {
+		if !ok || rg.Selected == "" || rg.Selected == string(current) {
+			return
+		}
+		if err := mui.ctl.SetNetwork(Network(rg.Selected)); err != nil {
+			dialog.ShowError(err, mui.as.w)
+			return
+		}
+		mui.mu.Lock()
+		mui.network = Network(rg.Selected)
+		mui.mu.Unlock()
+		mui.Refresh()
+	}, mui.as.w)
+}
+
+// setAutomine turns drivechain automining on or off.
+func (mui *MainUI) setAutomine(enabled bool) {
+	if err := mui.ctl.SetAutomine(enabled); err != nil {
+		dialog.ShowError(err, mui.as.w)
+		return
+	}
+	mui.as.setAutomine(enabled)
+	mui.Refresh()
+}
+
+// StartChainWithProgress starts a chain in the background. Launch errors are
+// shown in notice, download progress in the chain's row.
+func (mui *MainUI) StartChainWithProgress(name string, id string, notice *widget.RichText) {
+	pu := widget.NewModalPopUp(widget.NewLabel(fmt.Sprintf("Launching %s...", name)), mui.as.w.Canvas())
+	pu.Show()
+	time.AfterFunc(time.Duration(1)*time.Second, func() {
+		pu.Hide()
+	})
+	go func() {
+		err := mui.ctl.Start(id)
+		ShowLaunchError(notice, err)
+		mui.offerFreePorts(name, id, notice, err)
+		mui.Refresh()
+	}()
+}
+
+// offerFreePorts asks to move a chain that failed to start because of a
+// port conflict to free ports and start it again.
+func (mui *MainUI) offerFreePorts(name string, id string, notice *widget.RichText, err error) {
+	var le *LaunchError
+	if !errors.As(err, &le) || le.Kind != PortInUse {
+		return
+	}
+	msg := fmt.Sprintf("%s can't use port %d, %v.\n\nMove %s to free ports and start it?", name, le.Port, le.Err, name)
+	dialog.ShowConfirm("Port in use", msg, func(ok bool) {
+		if !ok {
+			return
+		}
+		go func() {
+			if _, err := mui.ctl.FixPorts([]string{id}); err != nil {
+				dialog.ShowError(err, mui.as.w)
+				return
+			}
+			mui.StartChainWithProgress(name, id, notice)
+		}()
+	}, mui.as.w)
+}
+
+// NewDownloadProgress returns the hidden bar showing artifact downloads.
+func NewDownloadProgress() *widget.ProgressBar {
+	pb := widget.NewProgressBar()
+	pb.TextFormatter = func() string {
+		return fmt.Sprintf("Downloading... %.0f%%", pb.Value*100)
+	}
+	pb.Hide()
+	return pb
+}
+
+// ShowDownload shows p in pb, or hides pb if no download is running.
+func ShowDownload(pb *widget.ProgressBar, p *DownloadProgress) {
+	if p == nil {
+		pb.Hide()
+		return
+	}
+	pb.SetValue(p.Fraction())
+	pb.Show()
+}
+
+// NewUpdateButton returns the hidden button updating a chain to the
+// catalogue version.
+func (mui *MainUI) NewUpdateButton(cp ChainProvider, notice *widget.RichText) *widget.Button {
+	b := widget.NewButtonWithIcon("Update", theme.DownloadIcon(), func() {
+		go func() {
+			err := mui.ctl.Update(cp.ID)
+			ShowLaunchError(notice, err)
+			mui.Refresh()
+		}()
+	})
+	b.Alignment = widget.ButtonAlignTrailing
+	b.IconPlacement = widget.ButtonIconTrailingText
+	b.Importance = widget.HighImportance
+	b.Hide()
+	return b
+}
+
+// ShowUpdate shows b if a newer version of the chain is in the catalogue.
+func ShowUpdate(b *widget.Button, vi VersionInfo) {
+	if !vi.UpdateAvailable {
+		b.Hide()
+		return
+	}
+	b.SetText("Update to " + vi.Latest)
+	b.Show()
+}
+
+func (mui *MainUI) versionInfo(id string) VersionInfo {
+	return mui.chainStatus(id).Versions
+}
+
+// chainStatus returns the last status of chain id watchStatus received.
+func (mui *MainUI) chainStatus(id string) ChainStatus {
+	mui.mu.Lock()
+	defer mui.mu.Unlock()
+	return mui.statuses[id]
+}
+
+// ShowVersions lets the user switch a chain to another installed version,
+// e.g. to roll back an update.
+func (mui *MainUI) ShowVersions(cp ChainProvider, notice *widget.RichText) {
+	vi := mui.versionInfo(cp.ID)
+	if len(vi.Installed) == 0 {
+		dialog.ShowInformation(cp.Name+" versions", "No versions of "+cp.Name+" installed yet.", mui.as.w)
+		return
+	}
+	rg := widget.NewRadioGroup(vi.Installed, nil)
+	rg.SetSelected(vi.Active)
+	dialog.ShowCustomConfirm(cp.Name+" versions", "Use", "Cancel", rg, func(ok bool) {
+		if !ok || rg.Selected == "" || rg.Selected == vi.Active {
+			return
+		}
+		go func() {
+			err := mui.ctl.UseVersion(cp.ID, rg.Selected)
+			ShowLaunchError(notice, err)
+			mui.Refresh()
+		}()
+	}, mui.as.w)
+}
+
+// NewVersionsButton returns the row button opening ShowVersions.
+func (mui *MainUI) NewVersionsButton(cp ChainProvider, notice *widget.RichText) *widget.Button {
+	b := widget.NewButton("Versions", func() {
+		mui.ShowVersions(cp, notice)
+	})
+	b.Importance = widget.LowImportance
+	return b
+}
+
+func (mui *MainUI) sidechainState(id string) ChainState {
+	cs, _ := mui.as.sidechainState(id)
+	return cs
+}
+
 func (mui *MainUI) Refresh() {
+	mui.refreshNetwork()
 	for _, scr := range mui.sideChainRows {
 		scr.Refresh(mui)
 	}
 	mui.driveChainRow.Refresh(mui)
 }
 
+// StopChainWithProgress stops a chain in the background, showing each step
+// of the stop sequence in a modal until the chain is down.
+func (mui *MainUI) StopChainWithProgress(name string, id string) {
+	lbl := widget.NewLabel(fmt.Sprintf("Stopping %s...", name))
+	pu := widget.NewModalPopUp(lbl, mui.as.w.Canvas())
+	pu.Show()
+	go func() {
+		err := mui.ctl.Stop(id, func(step string) {
+			lbl.SetText(step)
+		})
+		pu.Hide()
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("could not stop %s: %w", name, err), mui.as.w)
+		}
+		mui.Refresh()
+	}()
+}
+
+// NewNoticeText returns the hidden text used to show launch problems
+// inline in a chain row.
+func NewNoticeText() *widget.RichText {
+	rt := widget.NewRichTextWithText("")
+	rt.Segments[0].(*widget.TextSegment).Style = widget.RichTextStyle{
+		Alignment: fyne.TextAlignLeading,
+		SizeName:  theme.SizeNameText,
+		ColorName: theme.ColorNameError,
+		TextStyle: fyne.TextStyle{Italic: true, Bold: true},
+	}
+	rt.Wrapping = fyne.TextWrapWord
+	rt.Hide()
+	return rt
+}
+
+// ShowLaunchError shows err and the suggested fix in rt, or hides rt if err
+// is nil.
+func ShowLaunchError(rt *widget.RichText, err error) {
+	if err == nil {
+		rt.Hide()
+		return
+	}
+	println(err.Error())
+	text := err.Error()
+	var le *LaunchError
+	if errors.As(err, &le) && le.Fix != "" {
+		text += "\n" + le.Fix
+	}
+	rt.Segments[0].(*widget.TextSegment).Text = text
+	rt.Show()
+	rt.Refresh()
+}
+
 type DrivechainRow struct {
-	Title       *widget.RichText
-	Desc        *widget.RichText
-	Blocks      *widget.RichText
-	StartButton *widget.Button
-	StopButton  *widget.Button
-	MineButton  *widget.Button
+	Title        *widget.RichText
+	Desc         *widget.RichText
+	Blocks       *widget.RichText
+	Balance      *widget.RichText
+	Mempool      *widget.RichText
+	Notice       *widget.RichText
+	Download     *widget.ProgressBar
+	StartButton  *widget.Button
+	StopButton   *widget.Button
+	MineButton   *widget.Button
+	UpdateButton *widget.Button
 }
 
 func NewDrivechainRow(mui *MainUI, cp ChainProvider, c *fyne.Container) DrivechainRow {
+	notice := NewNoticeText()
+	dcs := mui.as.drivechainState()
 	dcr := DrivechainRow{
-		Title:  widget.NewRichTextWithText(cp.Name),
-		Desc:   widget.NewRichTextWithText(cp.Description),
-		Blocks: widget.NewRichTextWithText("Blocks: " + strconv.Itoa(mui.as.dcs.Height)),
+		Notice:   notice,
+		Download: NewDownloadProgress(),
+		Title:    widget.NewRichTextWithText(cp.Name),
+		Desc:     widget.NewRichTextWithText(cp.Description),
+		Blocks:   widget.NewRichTextWithText("Blocks: " + strconv.Itoa(dcs.Height)),
+		Balance:  widget.NewRichTextWithText(balanceText(dcs)),
+		Mempool:  widget.NewRichTextWithText(mempoolText(dcs)),
 		StartButton: widget.NewButtonWithIcon("Launch Chain", mui.as.t.Icon(StartIcon), func() {
-			pu := widget.NewModalPopUp(widget.NewLabel("Launching Drivechain..."), mui.as.w.Canvas())
-			pu.Show()
-			time.AfterFunc(time.Duration(1)*time.Second, func() {
-				pu.Hide()
-			})
-			LaunchChain(&mui.as.dcd, &mui.as.dcs, mui)
+			mui.StartChainWithProgress(cp.Name, cp.ID, notice)
 		}),
 		StopButton: widget.NewButtonWithIcon("Stop Chain", mui.as.t.Icon(StopIcon), func() {
-			mui.as.dcs.Automine = false
-			pu := widget.NewModalPopUp(widget.NewLabel("Stoping Drivechain..."), mui.as.w.Canvas())
-			pu.Show()
-			time.AfterFunc(time.Duration(1)*time.Second, func() {
-				pu.Hide()
-			})
-			StopChain(&mui.as.dcd, &mui.as.dcs, mui.as)
+			mui.StopChainWithProgress(cp.Name, cp.ID)
 		}),
 		MineButton: widget.NewButtonWithIcon("Start Mining", mui.as.t.Icon(MineIcon), func() {
-			mui.as.dcs.Automine = false
-			mui.Refresh()
+			mui.setAutomine(true)
 		}),
+		UpdateButton: mui.NewUpdateButton(cp, notice),
 	}
 
 	dcr.StartButton.Alignment = widget.ButtonAlignTrailing
@@ -177,14 +495,16 @@ func NewDrivechainRow(mui *MainUI, cp ChainProvider, c *fyne.Container) Drivecha
 	}
 	dcr.Desc.Wrapping = fyne.TextWrapWord
 
-	dcr.Blocks.Segments[0].(*widget.TextSegment).Style = widget.RichTextStyle{
-		Alignment: fyne.TextAlignLeading,
-		SizeName:  theme.SizeNameCaptionText,
-		ColorName: theme.ColorGray,
-		TextStyle: fyne.TextStyle{Italic: false, Bold: false},
+	for _, rt := range []*widget.RichText{dcr.Blocks, dcr.Balance, dcr.Mempool} {
+		rt.Segments[0].(*widget.TextSegment).Style = widget.RichTextStyle{
+			Alignment: fyne.TextAlignLeading,
+			SizeName:  theme.SizeNameCaptionText,
+			ColorName: theme.ColorGray,
+			TextStyle: fyne.TextStyle{Italic: false, Bold: false},
+		}
 	}
 
-	ftr := container.NewHBox(dcr.Blocks)
+	ftr := container.NewHBox(dcr.Blocks, dcr.Balance, dcr.Mempool)
 
 	bck := NewThemedRectangle(theme.ColorNameMenuBackground)
 	bck.CornerRadius = 8
@@ -202,17 +522,26 @@ func NewDrivechainRow(mui *MainUI, cp ChainProvider, c *fyne.Container) Drivecha
 	})
 	gitButton.Importance = widget.LowImportance
 
-	lbrdr := container.NewBorder(nil, container.NewHBox(gitButton), nil, nil, nil)
+	lbrdr := container.NewBorder(nil, container.NewHBox(gitButton, mui.NewSettingsButton(cp, notice), mui.NewVersionsButton(cp, notice), mui.NewWalletButton(cp), mui.NewResetButton(cp)), nil, nil, nil)
 
 	brdr := container.NewBorder(nil, container.NewVBox(&layout.Spacer{FixHorizontal: true, FixVertical: true}, widget.NewSeparator(), ftr), nil,
-		container.NewVBox(dcr.StartButton, dcr.StopButton, dcr.MineButton), container.NewVBox(dcr.Title, dcr.Desc, lbrdr))
+		container.NewVBox(dcr.StartButton, dcr.StopButton, dcr.MineButton, dcr.UpdateButton), container.NewVBox(dcr.Title, dcr.Desc, dcr.Notice, dcr.Download, lbrdr))
 	stk.Add(container.NewPadded(container.NewPadded(brdr)))
 	c.Add(stk)
 	return dcr
 }
 
 func (dcr *DrivechainRow) Refresh(mui *MainUI) {
-	if mui.as.dcs.State == Running {
+	ShowDownload(dcr.Download, mui.as.download(mui.drivechainID))
+	ShowUpdate(dcr.UpdateButton, mui.versionInfo(mui.drivechainID))
+	// Blocks can only be generated on regtest
+	if dn := mui.chainStatus(mui.drivechainID).Network; dn != "" && dn != Regtest.Section() {
+		dcr.MineButton.Hide()
+	} else {
+		dcr.MineButton.Show()
+	}
+	dcs := mui.as.drivechainState()
+	if dcs.State == Running {
 		dcr.StartButton.Disable()
 		dcr.MineButton.Enable()
 		dcr.StopButton.Enable()
@@ -221,25 +550,27 @@ func (dcr *DrivechainRow) Refresh(mui *MainUI) {
 		dcr.MineButton.Disable()
 		dcr.StopButton.Disable()
 	}
-	if mui.as.dcs.Automine {
+	if dcs.Automine {
 		dcr.MineButton.Importance = widget.MediumImportance
 		dcr.MineButton.SetText("Stop Mining")
 		dcr.MineButton.OnTapped = func() {
-			mui.as.dcs.Automine = false
-			mui.Refresh()
+			mui.setAutomine(false)
 		}
 		dcr.MineButton.Refresh()
 	} else {
 		dcr.MineButton.Importance = widget.HighImportance
 		dcr.MineButton.SetText("Start Mining")
 		dcr.MineButton.OnTapped = func() {
-			mui.as.dcs.Automine = true
-			mui.Refresh()
+			mui.setAutomine(true)
 		}
 		dcr.MineButton.Refresh()
 	}
-	mui.driveChainRow.Blocks.Segments[0].(*widget.TextSegment).Text = "Blocks: " + strconv.Itoa(mui.as.dcs.Height)
+	mui.driveChainRow.Blocks.Segments[0].(*widget.TextSegment).Text = "Blocks: " + strconv.Itoa(dcs.Height)
 	mui.driveChainRow.Blocks.Refresh()
+	mui.driveChainRow.Balance.Segments[0].(*widget.TextSegment).Text = balanceText(dcs)
+	mui.driveChainRow.Balance.Refresh()
+	mui.driveChainRow.Mempool.Segments[0].(*widget.TextSegment).Text = mempoolText(dcs)
+	mui.driveChainRow.Mempool.Refresh()
 	mui.contentContainer.Refresh()
 }
 
@@ -247,52 +578,34 @@ type SidechainRow struct {
 	Title         *widget.RichText
 	Desc          *widget.RichText
 	Blocks        *widget.RichText
+	Balance       *widget.RichText
+	Mempool       *widget.RichText
+	Notice        *widget.RichText
+	Download      *widget.ProgressBar
 	StartButton   *widget.Button
 	StopButton    *widget.Button
+	UpdateButton  *widget.Button
 	ChainProivder ChainProvider
 }
 
 func NewSidechainRow(mui *MainUI, cp ChainProvider, c *fyne.Container) SidechainRow {
+	notice := NewNoticeText()
 	scr := SidechainRow{
-		Title:  widget.NewRichTextWithText(cp.Name),
-		Desc:   widget.NewRichTextWithText(cp.Description),
-		Blocks: widget.NewRichTextWithText("Blocks: " + strconv.Itoa(mui.as.scs[cp.ID].Height)),
+		Notice:   notice,
+		Download: NewDownloadProgress(),
+		Title:    widget.NewRichTextWithText(cp.Name),
+		Desc:     widget.NewRichTextWithText(cp.Description),
+		Blocks:   widget.NewRichTextWithText("Blocks: " + strconv.Itoa(mui.sidechainState(cp.ID).Height)),
+		Balance:  widget.NewRichTextWithText(balanceText(mui.sidechainState(cp.ID))),
+		Mempool:  widget.NewRichTextWithText(mempoolText(mui.sidechainState(cp.ID))),
 		StartButton: widget.NewButtonWithIcon("Launch Chain", mui.as.t.Icon(StartIcon), func() {
-			cd := mui.as.scd[cp.ID]
-			cs := mui.as.scs[cp.ID]
-			if NeedsActivation(&cd, mui.as) {
-				CreateSidechainProposal(mui.as, &cd, &cs)
-				ap := widget.NewModalPopUp(widget.NewLabel(fmt.Sprintf("Activating %s...", cp.Name)), mui.as.w.Canvas())
-				ap.Show()
-				time.AfterFunc(time.Duration(2)*time.Second, func() {
-					ap.Hide()
-
-					pu := widget.NewModalPopUp(widget.NewLabel(fmt.Sprintf("Launching %s...", cp.Name)), mui.as.w.Canvas())
-					pu.Show()
-					time.AfterFunc(time.Duration(1)*time.Second, func() {
-						pu.Hide()
-					})
-					LaunchChain(&cd, &cs, mui)
-				})
-			} else {
-				pu := widget.NewModalPopUp(widget.NewLabel(fmt.Sprintf("Launching %s...", cp.Name)), mui.as.w.Canvas())
-				pu.Show()
-				time.AfterFunc(time.Duration(1)*time.Second, func() {
-					pu.Hide()
-				})
-				LaunchChain(&cd, &cs, mui)
-			}
+			// Sidechains are proposed and activated first if needed
+			mui.StartChainWithProgress(cp.Name, cp.ID, notice)
 		}),
 		StopButton: widget.NewButtonWithIcon("Stop Chain", mui.as.t.Icon(StopIcon), func() {
-			pu := widget.NewModalPopUp(widget.NewLabel(fmt.Sprintf("Stoping %s...", cp.Name)), mui.as.w.Canvas())
-			pu.Show()
-			time.AfterFunc(time.Duration(1)*time.Second, func() {
-				pu.Hide()
-			})
-			cd := mui.as.scd[cp.ID]
-			cs := mui.as.scs[cp.ID]
-			StopChain(&cd, &cs, mui.as)
+			mui.StopChainWithProgress(cp.Name, cp.ID)
 		}),
+		UpdateButton:  mui.NewUpdateButton(cp, notice),
 		ChainProivder: cp,
 	}
 
@@ -317,14 +630,16 @@ func NewSidechainRow(mui *MainUI, cp ChainProvider, c *fyne.Container) Sidechain
 	}
 	scr.Desc.Wrapping = fyne.TextWrapWord
 
-	scr.Blocks.Segments[0].(*widget.TextSegment).Style = widget.RichTextStyle{
-		Alignment: fyne.TextAlignLeading,
-		SizeName:  theme.SizeNameCaptionText,
-		ColorName: theme.ColorGray,
-		TextStyle: fyne.TextStyle{Italic: false, Bold: false},
+	for _, rt := range []*widget.RichText{scr.Blocks, scr.Balance, scr.Mempool} {
+		rt.Segments[0].(*widget.TextSegment).Style = widget.RichTextStyle{
+			Alignment: fyne.TextAlignLeading,
+			SizeName:  theme.SizeNameCaptionText,
+			ColorName: theme.ColorGray,
+			TextStyle: fyne.TextStyle{Italic: false, Bold: false},
+		}
 	}
 
-	ftr := container.NewHBox(scr.Blocks)
+	ftr := container.NewHBox(scr.Blocks, scr.Balance, scr.Mempool)
 
 	bck := NewThemedRectangle(theme.ColorNameMenuBackground)
 	bck.CornerRadius = 8
@@ -342,43 +657,46 @@ func NewSidechainRow(mui *MainUI, cp ChainProvider, c *fyne.Container) Sidechain
 	})
 	gitButton.Importance = widget.LowImportance
 
-	lbrdr := container.NewBorder(nil, nil, container.NewHBox(gitButton), nil, nil)
+	lbrdr := container.NewBorder(nil, nil, container.NewHBox(gitButton, mui.NewSettingsButton(cp, notice), mui.NewVersionsButton(cp, notice), mui.NewWalletButton(cp), mui.NewResetButton(cp)), nil, nil)
 
-	if cp.ID == "bitnames" {
-		imp := widget.NewRichTextWithText("You likely need to run: sudo apt install qtbase5-dev")
-		imp.Segments[0].(*widget.TextSegment).Style = widget.RichTextStyle{
-			Alignment: fyne.TextAlignLeading,
-			SizeName:  theme.SizeNameText,
-			ColorName: theme.ColorGray,
-			TextStyle: fyne.TextStyle{Italic: true, Bold: true},
-		}
-		imp.Wrapping = fyne.TextWrapWord
-
-		brdr := container.NewBorder(nil, container.NewVBox(&layout.Spacer{FixHorizontal: true, FixVertical: true}, widget.NewSeparator(), ftr), nil, container.NewVBox(scr.StartButton, scr.StopButton), container.NewVBox(scr.Title, scr.Desc, imp, lbrdr))
-		stk.Add(container.NewPadded(container.NewPadded(brdr)))
-	} else {
-		brdr := container.NewBorder(nil, container.NewVBox(&layout.Spacer{FixHorizontal: true, FixVertical: true}, widget.NewSeparator(), ftr), nil, container.NewVBox(scr.StartButton, scr.StopButton), container.NewVBox(scr.Title, scr.Desc, lbrdr))
-		stk.Add(container.NewPadded(container.NewPadded(brdr)))
-	}
+	brdr := container.NewBorder(nil, container.NewVBox(&layout.Spacer{FixHorizontal: true, FixVertical: true}, widget.NewSeparator(), ftr), nil, container.NewVBox(scr.StartButton, scr.StopButton, scr.UpdateButton), container.NewVBox(scr.Title, scr.Desc, scr.Notice, scr.Download, lbrdr))
+	stk.Add(container.NewPadded(container.NewPadded(brdr)))
 
 	c.Add(stk)
 	return scr
 }
 
 func (scr *SidechainRow) Refresh(mui *MainUI) {
-	if mui.as.dcs.State != Running {
+	ShowDownload(scr.Download, mui.as.download(scr.ChainProivder.ID))
+	ShowUpdate(scr.UpdateButton, mui.versionInfo(scr.ChainProivder.ID))
+	if mui.as.drivechainState().State != Running {
 		scr.StartButton.Disable()
 		scr.StopButton.Disable()
 		return
 	}
-	if mui.as.scs[scr.ChainProivder.ID].State == Running {
+	if mui.sidechainState(scr.ChainProivder.ID).State == Running {
 		scr.StartButton.Disable()
 		scr.StopButton.Enable()
 	} else {
 		scr.StartButton.Enable()
 		scr.StopButton.Disable()
 	}
-	scr.Blocks.Segments[0].(*widget.TextSegment).Text = "Blocks: " + strconv.Itoa(mui.as.scs[scr.ChainProivder.ID].Height)
+	scr.Blocks.Segments[0].(*widget.TextSegment).Text = "Blocks: " + strconv.Itoa(mui.sidechainState(scr.ChainProivder.ID).Height)
 	scr.Blocks.Refresh()
+	scr.Balance.Segments[0].(*widget.TextSegment).Text = balanceText(mui.sidechainState(scr.ChainProivder.ID))
+	scr.Balance.Refresh()
+	scr.Mempool.Segments[0].(*widget.TextSegment).Text = mempoolText(mui.sidechainState(scr.ChainProivder.ID))
+	scr.Mempool.Refresh()
 	mui.contentContainer.Refresh()
 }
+
+func balanceText(cs ChainState) string {
+	if cs.PendingBalance != 0 {
+		return fmt.Sprintf("Balance: %.8f (%.8f pending)", cs.AvailableBalance, cs.PendingBalance)
+	}
+	return fmt.Sprintf("Balance: %.8f", cs.AvailableBalance)
+}
+
+func mempoolText(cs ChainState) string {
+	return "Mempool: " + strconv.Itoa(cs.MempoolSize)
+}
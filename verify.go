@@ -0,0 +1,165 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// VerifyReport is the result of checking the active version of a chain
+// against its install manifest.
+type VerifyReport struct {
+	ID       string   `json:"id"`
+	Version  string   `json:"version,omitempty"`
+	Files    int      `json:"files"`  // Number of files checked
+	Signed   bool     `json:"signed"` // The download carried a valid signature
+	Problems []string `json:"problems,omitempty"`
+	Note     string   `json:"note,omitempty"` // Why nothing could be checked
+}
+
+func (r VerifyReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// hashInstall returns the sha256 of every file installed in dir, by path
+// relative to dir.
+func hashInstall(dir string) (map[string]string, error) {
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = sum
+		return nil
+	})
+	return files, err
+}
+
+// VerifyChain checks that the files of the active version of cd are the
+// ones the launcher installed. Files the chain added later are ignored.
+func VerifyChain(cd *ChainData) (VerifyReport, error) {
+	r := VerifyReport{ID: cd.ID}
+	m, err := loadManifest(cd)
+	if err != nil {
+		return r, err
+	}
+	if m.Active == "" {
+		r.Note = "not installed by the launcher, nothing to verify"
+		return r, nil
+	}
+	r.Version = m.Active
+	iv, ok := m.find(m.Active)
+	if !ok {
+		r.Problems = append(r.Problems, fmt.Sprintf("active version %s is not in %s", m.Active, installManifestName))
+		return r, nil
+	}
+	r.Signed = iv.Signed
+	if len(iv.Files) == 0 {
+		r.Note = "installed without checksums by an older launcher, delete it to download it again"
+		return r, nil
+	}
+
+	var paths []string
+	for rel := range iv.Files {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	dir := versionDir(cd, iv.Version)
+	for _, rel := range paths {
+		sum, err := fileSHA256(filepath.Join(dir, filepath.FromSlash(rel)))
+		switch {
+		case os.IsNotExist(err):
+			r.Problems = append(r.Problems, rel+" is missing")
+		case err != nil:
+			r.Problems = append(r.Problems, err.Error())
+		case !strings.EqualFold(sum, iv.Files[rel]):
+			r.Problems = append(r.Problems, fmt.Sprintf("%s was modified, sha256 is %s, expected %s", rel, sum, iv.Files[rel]))
+		}
+		r.Files++
+	}
+	return r, nil
+}
+
+// checkInstall verifies cd before it is started. A mismatch stops the
+// launch unless verifyBinaries is "warn" in launcher.json.
+func checkInstall(cd *ChainData) error {
+	r, err := VerifyChain(cd)
+	if err != nil {
+		return err
+	}
+	if r.Note != "" {
+		println(cd.ID + ": " + r.Note)
+	}
+	if r.OK() {
+		return nil
+	}
+
+	problems := strings.Join(r.Problems, ", ")
+	settings, err := LoadSettings()
+	if err != nil {
+		println(err.Error())
+	}
+	if settings.VerifyBinaries == "warn" {
+		println(fmt.Sprintf("WARNING: %s %s does not match what the launcher installed, starting it anyway: %s", cd.ID, r.Version, problems))
+		return nil
+	}
+	return &LaunchError{
+		ChainID: cd.ID,
+		Kind:    IntegrityFailed,
+		Path:    versionDir(cd, r.Version),
+		Fix:     "Delete this directory to download the chain again, or set \"verifyBinaries\": \"warn\" in launcher.json to start it anyway",
+		Err:     errors.New(problems),
+	}
+}
+
+// parsePublicKey decodes a base64 ed25519 public key.
+func parsePublicKey(k string) (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(k))
+	if err != nil || len(b) != ed25519.PublicKeySize {
+		return nil, errors.New("not a base64 ed25519 public key")
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// verifyArtifactSignature checks the publisher's signature of a, a base64
+// ed25519 signature over its raw sha256 hash, and reports whether a was
+// signed. Artifacts of chains without a signingKey are only checked by hash.
+func verifyArtifactSignature(cd *ChainData, a Artifact) (bool, error) {
+	if cd.SigningKey == "" {
+		return false, nil
+	}
+	key, err := parsePublicKey(cd.SigningKey)
+	if err != nil {
+		return false, fmt.Errorf("signingKey of %s: %w", cd.ID, err)
+	}
+	if a.Signature == "" {
+		return false, errors.New("the download is not signed")
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(a.Signature))
+	if err != nil {
+		return false, fmt.Errorf("download signature: %w", err)
+	}
+	sum, err := hex.DecodeString(a.SHA256)
+	if err != nil {
+		return false, err
+	}
+	if !ed25519.Verify(key, sum, sig) {
+		return false, errors.New("download signature does not match the signingKey")
+	}
+	return true, nil
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	installManifestName = "dclauncher.installed.json"
+	versionsDirName     = "versions"
+)
+
+type InstalledVersion struct {
+	Version     string    `json:"version"`
+	SHA256      string    `json:"sha256"`
+	InstalledAt time.Time `json:"installedAt"`
+	Signed      bool      `json:"signed,omitempty"`
+	// sha256 of every installed file, by path relative to the version
+	// directory
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// InstallManifest lists the versions of a chain installed side by side in
+// its versions directory and which of them is started.
+type InstallManifest struct {
+	Active   string             `json:"active,omitempty"`
+	Versions []InstalledVersion `json:"versions,omitempty"`
+}
+
+func (m *InstallManifest) find(version string) (InstalledVersion, bool) {
+	for _, v := range m.Versions {
+		if v.Version == version {
+			return v, true
+		}
+	}
+	return InstalledVersion{}, false
+}
+
+func (m *InstallManifest) versionNames() []string {
+	var names []string
+	for _, v := range m.Versions {
+		names = append(names, v.Version)
+	}
+	return names
+}
+
+func loadManifest(cd *ChainData) (InstallManifest, error) {
+	var m InstallManifest
+	b, err := os.ReadFile(filepath.Join(cd.ConfDir, installManifestName))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(b, &m)
+	return m, err
+}
+
+func saveManifest(cd *ChainData, m InstallManifest) error {
+	b, err := json.MarshalIndent(m, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(cd.ConfDir, installManifestName), append(b, '\n'), 0o644)
+}
+
+func versionDir(cd *ChainData, version string) string {
+	return filepath.Join(cd.ConfDir, versionsDirName, version)
+}
+
+// applyManifest points cd at the active version, if any.
+func applyManifest(cd *ChainData, m InstallManifest) {
+	if m.Active == "" {
+		return
+	}
+	cd.InstallDir = versionDir(cd, m.Active)
+	cd.BinDir = cd.Driver().BinDir(cd.InstallDir)
+}
+
+// catalogueVersion returns the artifact for this platform and its version.
+// Catalogues without versions are told apart by hash.
+func catalogueVersion(cd *ChainData) (Artifact, string, bool) {
+	a, ok := cd.Artifacts[platformKey()]
+	if !ok {
+		return a, "", false
+	}
+	version := cd.Version
+	if version == "" && len(a.SHA256) >= 12 {
+		version = strings.ToLower(a.SHA256[:12])
+	}
+	return a, version, true
+}
+
+func validVersion(version string) bool {
+	return version != "" && version != "." && version != ".." && !strings.ContainsAny(version, `/\`)
+}
+
+// installVersion downloads the catalogue version of cd into its own
+// directory and adds it to the manifest, without making it active.
+func installVersion(cd *ChainData, as *AppState) (string, error) {
+	a, version, ok := catalogueVersion(cd)
+	if !ok {
+		return "", fmt.Errorf("the chain catalogue has no download of %s for %s", cd.ID, platformKey())
+	}
+	if !validVersion(version) {
+		return "", fmt.Errorf("%s: invalid version %q in the chain catalogue", cd.ID, version)
+	}
+	m, err := loadManifest(cd)
+	if err != nil {
+		return "", err
+	}
+	dir := versionDir(cd, version)
+	if iv, ok := m.find(version); ok && strings.EqualFold(iv.SHA256, a.SHA256) {
+		if _, err := os.Stat(dir); err == nil {
+			return version, nil
+		}
+	}
+	if err := checkReplaceable(cd, as, m, version); err != nil {
+		return "", err
+	}
+
+	println(fmt.Sprintf("Downloading %s %s from %s", cd.ID, version, a.URL))
+	as.setDownload(cd.ID, &DownloadProgress{Total: a.Size})
+	defer func() {
+		as.setDownload(cd.ID, nil)
+		as.Refresh()
+	}()
+	path, err := DownloadArtifact(context.Background(), a, func(p DownloadProgress) {
+		as.setDownload(cd.ID, &p)
+		as.Refresh()
+	})
+	if err != nil {
+		return "", &LaunchError{
+			ChainID: cd.ID,
+			Kind:    DownloadFailed,
+			Path:    a.URL,
+			Fix:     "Check your internet connection and try again, the download resumes where it stopped",
+			Err:     err,
+		}
+	}
+	signed, err := verifyArtifactSignature(cd, a)
+	if err != nil {
+		return "", &LaunchError{
+			ChainID: cd.ID,
+			Kind:    IntegrityFailed,
+			Path:    a.URL,
+			Fix:     "The download is not from the chain's publisher, check the chain catalogue",
+			Err:     err,
+		}
+	}
+
+	err = addVersion(cd, version, a.SHA256, signed, func(dir string) error {
+		return installArtifact(cd, a, path, dir)
+	})
+	return version, err
+}
+
+// checkReplaceable refuses to replace the files of an installed version
+// the chain runs from, a version republished with a different hash would
+// otherwise be deleted under it.
+func checkReplaceable(cd *ChainData, as *AppState, m InstallManifest, version string) error {
+	dir := versionDir(cd, version)
+	if _, err := os.Stat(dir); err != nil {
+		return nil
+	}
+	if as.sup.IsRunning(cd.ID) {
+		return fmt.Errorf("%s %s was republished with different files, stop %s before installing it again", cd.ID, version, cd.ID)
+	}
+	if m.Active == version || filepath.Clean(cd.InstallDir) == dir {
+		return fmt.Errorf("%s %s was republished with different files, switch %s to another version before installing it again", cd.ID, version, cd.ID)
+	}
+	return nil
+}
+
+// addVersion installs version into its directory with install and records
+// it in the manifest.
+func addVersion(cd *ChainData, version string, sum string, signed bool, install func(dir string) error) error {
+	m, err := loadManifest(cd)
+	if err != nil {
+		return err
+	}
+	dir := versionDir(cd, version)
+	// A version republished with a different hash replaces the old files
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := install(dir); err != nil {
+		return &LaunchError{ChainID: cd.ID, Kind: LaunchFailed, Path: dir, Err: err}
+	}
+	files, err := hashInstall(dir)
+	if err != nil {
+		return err
+	}
+
+	var versions []InstalledVersion
+	for _, v := range m.Versions {
+		if v.Version != version {
+			versions = append(versions, v)
+		}
+	}
+	m.Versions = append(versions, InstalledVersion{
+		Version:     version,
+		SHA256:      strings.ToLower(sum),
+		InstalledAt: time.Now(),
+		Signed:      signed,
+		Files:       files,
+	})
+	return saveManifest(cd, m)
+}
+
+// EnsureInstalled makes sure cd has a version to start. The active version
+// is kept even if the catalogue has a newer one, updating is up to the
+// user. Chains without an artifact must have their binary put in place by
+// hand, unless the launcher was built with the binaries built in.
+func EnsureInstalled(cd *ChainData, as *AppState) error {
+	m, err := loadManifest(cd)
+	if err != nil {
+		return err
+	}
+	if m.Active != "" {
+		if _, err := os.Stat(versionDir(cd, m.Active)); err == nil {
+			applyManifest(cd, m)
+			return nil
+		}
+	}
+
+	if _, _, ok := catalogueVersion(cd); !ok {
+		if _, err := os.Stat(filepath.Join(cd.BinDir, cd.BinName)); err == nil {
+			return nil
+		}
+		if version, ok, err := installBuiltin(cd, as); ok {
+			if err != nil {
+				return err
+			}
+			return activateVersion(cd, version)
+		}
+		return &LaunchError{
+			ChainID: cd.ID,
+			Kind:    BinaryMissing,
+			Path:    filepath.Join(cd.BinDir, cd.BinName),
+			Fix:     fmt.Sprintf("The chain catalogue has no download of %s for %s, put the binary at this path", cd.ID, platformKey()),
+		}
+	}
+
+	version, err := installVersion(cd, as)
+	if err != nil {
+		return err
+	}
+	return activateVersion(cd, version)
+}
+
+func activateVersion(cd *ChainData, version string) error {
+	m, err := loadManifest(cd)
+	if err != nil {
+		return err
+	}
+	if _, ok := m.find(version); !ok {
+		return fmt.Errorf("%s %s is not installed, installed versions: %s", cd.ID, version, strings.Join(m.versionNames(), ", "))
+	}
+	m.Active = version
+	if err := saveManifest(cd, m); err != nil {
+		return err
+	}
+	applyManifest(cd, m)
+	return nil
+}
+
+// VersionInfo describes the installed versions of a chain.
+type VersionInfo struct {
+	Active          string   `json:"active,omitempty"`
+	Latest          string   `json:"latest,omitempty"`
+	UpdateAvailable bool     `json:"updateavailable,omitempty"`
+	Installed       []string `json:"installed,omitempty"`
+}
+
+func ChainVersionInfo(cd *ChainData) VersionInfo {
+	m, err := loadManifest(cd)
+	if err != nil {
+		println(err.Error())
+	}
+	vi := VersionInfo{Active: m.Active, Installed: m.versionNames()}
+	a, latest, ok := catalogueVersion(cd)
+	if !ok {
+		return vi
+	}
+	vi.Latest = latest
+	if m.Active != "" {
+		active, _ := m.find(m.Active)
+		vi.UpdateAvailable = !strings.EqualFold(active.SHA256, a.SHA256)
+	}
+	return vi
+}
+
+// UseChainVersion switches chain id to an installed version, restarting it
+// if it is running. Switching to an older version is how updates are rolled
+// back.
+func UseChainVersion(as *AppState, id string, version string) error {
+	cd, cs, err := chainByID(as, id)
+	if err != nil {
+		return err
+	}
+	running := as.sup.IsRunning(id)
+	if running {
+		if err := StopChain(cd, cs, as, nil); err != nil {
+			return err
+		}
+	}
+	if err := activateVersion(cd, version); err != nil {
+		return err
+	}
+	as.setChainData(cd)
+	println(fmt.Sprintf("%s now uses version %s", id, version))
+	if running {
+		return LaunchChain(cd, cs, as)
+	}
+	return nil
+}
+
+// UpdateChain installs the catalogue version of chain id and switches to it.
+func UpdateChain(as *AppState, id string) error {
+	cd, _, err := chainByID(as, id)
+	if err != nil {
+		return err
+	}
+	version, err := installVersion(cd, as)
+	if err != nil {
+		return err
+	}
+	return UseChainVersion(as, id, version)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCheckReplaceable(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		installed bool
+		active    bool
+		installIn bool // cd.InstallDir is the version's directory
+		running   bool
+		ok        bool
+	}{
+		{"not installed", false, false, false, false, true},
+		{"not installed while running", false, false, false, true, true},
+		{"installed", true, false, false, false, true},
+		{"active", true, true, false, false, false},
+		{"started from it", true, false, true, false, false},
+		{"running", true, false, false, true, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			as := &AppState{sup: NewSupervisor()}
+			cd := &ChainData{ID: "testchain", ConfDir: t.TempDir()}
+			cd.InstallDir = cd.ConfDir
+			var m InstallManifest
+			if tt.installed {
+				if err := os.MkdirAll(versionDir(cd, "1.0"), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.active {
+				m.Active = "1.0"
+			}
+			if tt.installIn {
+				cd.InstallDir = versionDir(cd, "1.0")
+			}
+			if tt.running {
+				// Any live process will do
+				if _, err := as.sup.Adopt(cd.ID, os.Getpid()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := checkReplaceable(cd, as, m, "1.0")
+			if (err == nil) != tt.ok {
+				t.Errorf("checkReplaceable = %v", err)
+			}
+		})
+	}
+}
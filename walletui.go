@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// NewWalletButton returns the row button opening ShowWalletBackup.
+func (mui *MainUI) NewWalletButton(cp ChainProvider) *widget.Button {
+	b := widget.NewButton("Wallet", func() {
+		mui.ShowWalletBackup(cp)
+	})
+	b.Importance = widget.LowImportance
+	return b
+}
+
+// ShowWalletBackup backs up the wallet of a chain or restores one of its
+// backups.
+func (mui *MainUI) ShowWalletBackup(cp ChainProvider) {
+	backups, err := mui.ctl.WalletBackups(cp.ID)
+	if err != nil {
+		dialog.ShowError(err, mui.as.w)
+		return
+	}
+	var d dialog.Dialog
+
+	encrypt := widget.NewCheck("Encrypt with a passphrase", nil)
+	pass := widget.NewPasswordEntry()
+	pass.SetPlaceHolder("Passphrase")
+	again := widget.NewPasswordEntry()
+	again.SetPlaceHolder("Repeat the passphrase")
+	pass.Disable()
+	again.Disable()
+	encrypt.OnChanged = func(on bool) {
+		if on {
+			pass.Enable()
+			again.Enable()
+		} else {
+			pass.Disable()
+			again.Disable()
+		}
+	}
+	backupButton := widget.NewButton("Back up now", func() {
+		var opts BackupOptions
+		if encrypt.Checked {
+			if pass.Text == "" || pass.Text != again.Text {
+				dialog.ShowError(errors.New("the passphrases are empty or differ"), mui.as.w)
+				return
+			}
+			opts.Passphrase = pass.Text
+		}
+		d.Hide()
+		mui.runWalletTask(fmt.Sprintf("Backing up the wallet of %s...", cp.Name), func() (string, error) {
+			b, err := mui.ctl.BackupWallet(cp.ID, opts)
+			return "The wallet was backed up to\n" + b.Path, err
+		})
+	})
+
+	byName := make(map[string]WalletBackup)
+	var names []string
+	for _, b := range backups {
+		name := filepath.Base(b.Path)
+		byName[name] = b
+		names = append(names, name)
+	}
+	restorePass := widget.NewPasswordEntry()
+	restorePass.SetPlaceHolder("Passphrase of the backup")
+	restorePass.Disable()
+	restoreButton := widget.NewButton("Restore...", nil)
+	restoreButton.Disable()
+	sel := widget.NewSelect(names, func(name string) {
+		restoreButton.Enable()
+		if byName[name].Encrypted {
+			restorePass.Enable()
+		} else {
+			restorePass.SetText("")
+			restorePass.Disable()
+		}
+	})
+	sel.PlaceHolder = "Choose a backup"
+	if len(names) == 0 {
+		sel.PlaceHolder = "No backups yet"
+		sel.Disable()
+	}
+	restoreButton.OnTapped = func() {
+		b := byName[sel.Selected]
+		if b.Encrypted && restorePass.Text == "" {
+			dialog.ShowError(errors.New("the backup is encrypted, enter its passphrase"), mui.as.w)
+			return
+		}
+		d.Hide()
+		mui.confirmWalletRestore(cp, RestoreOptions{File: b.Path, Passphrase: restorePass.Text})
+	}
+
+	content := container.NewVBox(
+		widget.NewLabel("Back up the wallet to the backups directory"),
+		encrypt, pass, again, backupButton,
+		widget.NewSeparator(),
+		widget.NewLabel("Restore a backup"),
+		sel, restorePass, restoreButton,
+	)
+	d = dialog.NewCustom("Wallet of "+cp.Name, "Close", content, mui.as.w)
+	d.Show()
+}
+
+// confirmWalletRestore explains what a restore does and runs it.
+func (mui *MainUI) confirmWalletRestore(cp ChainProvider, opts RestoreOptions) {
+	msg := fmt.Sprintf("The wallet of %s is replaced by\n%s\nThe current wallet is backed up first.", cp.Name, filepath.Base(opts.File))
+	if mui.isRunning(cp.ID) {
+		msg = fmt.Sprintf("%s is stopped, its wallet replaced by\n%s\nand it is started again. The current wallet is backed up first.", cp.Name, filepath.Base(opts.File))
+	}
+	dialog.ShowConfirm("Restore wallet of "+cp.Name, msg, func(ok bool) {
+		if !ok {
+			return
+		}
+		mui.runWalletTask(fmt.Sprintf("Restoring the wallet of %s...", cp.Name), func() (string, error) {
+			replaced, err := mui.ctl.RestoreWallet(cp.ID, opts)
+			if err != nil {
+				return "", err
+			}
+			if replaced.Path == "" {
+				return "The wallet was restored.", nil
+			}
+			return "The wallet was restored, the previous one was backed up to\n" + replaced.Path, nil
+		})
+	}, mui.as.w)
+}
+
+// runWalletTask runs a backup or restore behind a modal and shows its
+// outcome.
+func (mui *MainUI) runWalletTask(title string, task func() (string, error)) {
+	pu := widget.NewModalPopUp(widget.NewLabel(title), mui.as.w.Canvas())
+	pu.Show()
+	go func() {
+		msg, err := task()
+		pu.Hide()
+		if err != nil {
+			dialog.ShowError(err, mui.as.w)
+		} else {
+			dialog.ShowInformation("Wallet", msg, mui.as.w)
+		}
+		mui.Refresh()
+	}()
+}